@@ -0,0 +1,299 @@
+package outbound_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/client_lambda_proxy"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/client_rest"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+	"github.com/tecmise/connector-lib/pkg/ports/output/constant"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+)
+
+// The matrix sends every body kind with every method carrying a body through
+// every transport, and checks that the backend always receives the same
+// request: switching SCHOOL_CONNECTOR_TYPE must not change what it sees.
+
+type (
+	// received is the request as seen by the backend, whatever the transport.
+	received struct {
+		method      string
+		contentType string
+		body        []byte
+	}
+
+	recorder struct {
+		mu   sync.Mutex
+		last *received
+	}
+
+	response map[string]interface{}
+
+	// send issues one call with body through a transport.
+	send func(t *testing.T, method string, body interface{})
+
+	// proxyEvent holds the fields of both API Gateway payload formats that
+	// describe the request.
+	proxyEvent struct {
+		Version         string            `json:"version"`
+		HttpMethod      string            `json:"httpMethod"`
+		Headers         map[string]string `json:"headers"`
+		Body            string            `json:"body"`
+		IsBase64Encoded bool              `json:"isBase64Encoded"`
+		RequestContext  struct {
+			HTTP struct {
+				Method string `json:"method"`
+			} `json:"http"`
+		} `json:"requestContext"`
+	}
+)
+
+var bodyKinds = []struct {
+	name        string
+	body        func() interface{}
+	contentType string
+	// data is the expected body, or the expected fields for multipart bodies
+	data   string
+	fields map[string]string
+}{
+	{
+		name:        "json",
+		body:        func() interface{} { return map[string]string{"name": "a"} },
+		contentType: "application/json",
+		data:        `{"name":"a"}`,
+	},
+	{
+		name:        "raw bytes",
+		body:        func() interface{} { return []byte{0x00, 0xff, 0x10, 0x80} },
+		contentType: "application/octet-stream",
+		data:        "\x00\xff\x10\x80",
+	},
+	{
+		name:        "reader",
+		body:        func() interface{} { return io.Reader(bytes.NewReader([]byte("%PDF-1.7\x00\xff"))) },
+		contentType: "application/octet-stream",
+		data:        "%PDF-1.7\x00\xff",
+	},
+	{
+		name:        "form",
+		body:        func() interface{} { return &multipart.Form{Value: map[string][]string{"kind": {"report"}}} },
+		contentType: "multipart/form-data",
+		fields:      map[string]string{"kind": "report"},
+	},
+	{
+		name: "multipart",
+		body: func() interface{} {
+			return shared_kernel.NewMultipart().
+				Field("kind", "report").
+				File("file", "report.txt", "text/plain", strings.NewReader("file content"))
+		},
+		contentType: "multipart/form-data",
+		fields:      map[string]string{"kind": "report", "file": "file content"},
+	},
+}
+
+var bodyMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+func TestBodyEncodingMatrix(t *testing.T) {
+	rec := &recorder{}
+	rest := httptest.NewServer(http.HandlerFunc(rec.serveREST))
+	defer rest.Close()
+	fake := httptest.NewServer(http.HandlerFunc(rec.serveLambda))
+	defer fake.Close()
+
+	lambdaClient := lambda.New(lambda.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(fake.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		RetryMaxAttempts: 1,
+	})
+	client_lambda_proxy.LambdaClients[constant.USEast1] = lambdaClient
+
+	transports := map[string]send{
+		"rest v1":                     restV1(shared_kernel.WithBaseURL(rest.URL)),
+		"rest v2":                     restV2(shared_kernel.WithBaseURL(rest.URL)),
+		"lambda-proxy v1":             lambdaProxyV1(),
+		"lambda-proxy v2":             lambdaProxyV2(lambdaClient),
+		"lambda-proxy v1 payload 2.0": lambdaProxyV1(shared_kernel.WithPayloadVersion(lambda2.PayloadVersion2)),
+		"lambda-proxy v2 payload 2.0": lambdaProxyV2(lambdaClient, shared_kernel.WithPayloadVersion(lambda2.PayloadVersion2)),
+	}
+
+	for _, kind := range bodyKinds {
+		for _, method := range bodyMethods {
+			for name, transport := range transports {
+				t.Run(kind.name+"/"+method+"/"+name, func(t *testing.T) {
+					rec.reset()
+					transport(t, method, kind.body())
+
+					got := rec.get(t)
+					if got.method != method {
+						t.Errorf("method = %q, want %q", got.method, method)
+					}
+					mediaType, params, err := mime.ParseMediaType(got.contentType)
+					if err != nil || mediaType != kind.contentType {
+						t.Fatalf("content type = %q, want %q", got.contentType, kind.contentType)
+					}
+					if kind.fields == nil {
+						if string(got.body) != kind.data {
+							t.Errorf("body = %q, want %q", got.body, kind.data)
+						}
+						return
+					}
+					if fields := multipartFields(t, got.body, params["boundary"]); !equalFields(fields, kind.fields) {
+						t.Errorf("fields = %v, want %v", fields, kind.fields)
+					}
+				})
+			}
+		}
+	}
+}
+
+func restV1(opts ...shared_kernel.Option) send {
+	call := client_rest.NewConnector[response](opts...)
+	return func(t *testing.T, method string, body interface{}) {
+		var out response
+		parameter := connector.Parameter{Resource: "items", Method: method, Body: body}
+		if err := call.Create(parameter, &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func restV2(opts ...shared_kernel.Option) send {
+	client := client_rest.NewClient[interface{}, response]("", opts...)
+	return func(t *testing.T, method string, body interface{}) {
+		var err error
+		switch method {
+		case http.MethodPost:
+			_, err = client.POST(context.Background(), "items", &body, nil)
+		case http.MethodPut:
+			_, err = client.PUT(context.Background(), "items", &body, nil)
+		case http.MethodPatch:
+			_, err = client.PATCH(context.Background(), "items", &body, nil)
+		case http.MethodDelete:
+			_, err = client.DELETE(context.Background(), "items", &body, nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func lambdaProxyV1(opts ...shared_kernel.Option) send {
+	call := client_lambda_proxy.NewConnector[response](opts...)
+	return func(t *testing.T, method string, body interface{}) {
+		var out response
+		parameter := connector.Parameter{Host: "items-function", Resource: "items", Method: method, Body: body, Region: constant.USEast1}
+		if err := call.Create(parameter, &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func lambdaProxyV2(lambdaClient *lambda.Client, opts ...shared_kernel.Option) send {
+	client := client_lambda_proxy.NewClient[interface{}, response](lambdaClient, "items-function", "items", opts...)
+	return func(t *testing.T, method string, body interface{}) {
+		if _, err := client.Invoke(context.Background(), method, &body); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (r *recorder) serveREST(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.set(&received{method: req.Method, contentType: req.Header.Get("Content-Type"), body: body})
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{}`))
+}
+
+// serveLambda fakes the Lambda Invoke API, answering like a function behind
+// API Gateway.
+func (r *recorder) serveLambda(w http.ResponseWriter, req *http.Request) {
+	var event proxyEvent
+	if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	got := &received{method: event.HttpMethod, body: []byte(event.Body)}
+	if event.Version == "2.0" {
+		got.method = event.RequestContext.HTTP.Method
+	}
+	for key, value := range event.Headers {
+		if strings.EqualFold(key, "Content-Type") {
+			if got.contentType != "" {
+				got.contentType = "duplicate content-type headers"
+				break
+			}
+			got.contentType = value
+		}
+	}
+	if event.IsBase64Encoded {
+		got.body, _ = base64.StdEncoding.DecodeString(event.Body)
+	}
+	r.set(got)
+
+	_, _ = w.Write([]byte(`{"statusCode":200,"headers":{"Content-Type":"application/json"},"body":"{}"}`))
+}
+
+func (r *recorder) set(got *received) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = got
+}
+
+func (r *recorder) reset() {
+	r.set(nil)
+}
+
+func (r *recorder) get(t *testing.T) received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		t.Fatal("the backend received no request")
+	}
+	return *r.last
+}
+
+func multipartFields(t *testing.T, body []byte, boundary string) map[string]string {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return fields
+		}
+		if err != nil {
+			t.Fatalf("reading multipart body: %v", err)
+		}
+		data, _ := io.ReadAll(part)
+		fields[part.FormName()] = string(data)
+	}
+}
+
+func equalFields(got, want map[string]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for key, value := range want {
+		if got[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	Entry struct {
+		Body []byte
+		// ContentType is the media type of Body, needed to decode it again
+		// when the client negotiates its codec.
+		ContentType string
+		ETag        string
+		StoredAt    time.Time
+		ExpiresAt   time.Time
+	}
+
+	// Backend stores cached responses. Implementations must be safe for
+	// concurrent use and may evict entries at any time.
+	Backend interface {
+		Get(ctx context.Context, key string) (*Entry, bool)
+		Set(ctx context.Context, key string, entry *Entry)
+		Delete(ctx context.Context, key string)
+	}
+
+	memoryBackend struct {
+		mu         sync.Mutex
+		maxEntries int
+		items      map[string]*list.Element
+		order      *list.List
+	}
+
+	memoryItem struct {
+		key   string
+		entry *Entry
+	}
+)
+
+func (e *Entry) Fresh(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+// NewMemoryBackend returns an in-memory LRU backend holding at most
+// maxEntries responses. Zero or a negative value means unbounded.
+func NewMemoryBackend(maxEntries int) Backend {
+	return &memoryBackend{
+		maxEntries: maxEntries,
+		items:      map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (m *memoryBackend) Get(_ context.Context, key string) (*Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := element.Value.(*memoryItem)
+	// expired entries are only worth keeping when they can be revalidated
+	if item.entry.ETag == "" && !item.entry.Fresh(time.Now()) {
+		m.remove(element)
+		return nil, false
+	}
+	m.order.MoveToFront(element)
+	return item.entry, true
+}
+
+func (m *memoryBackend) Set(_ context.Context, key string, entry *Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.items[key]; ok {
+		element.Value.(*memoryItem).entry = entry
+		m.order.MoveToFront(element)
+		return
+	}
+	m.items[key] = m.order.PushFront(&memoryItem{key: key, entry: entry})
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *memoryBackend) Delete(_ context.Context, key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.items[key]; ok {
+		m.remove(element)
+	}
+}
+
+func (m *memoryBackend) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.items, element.Value.(*memoryItem).key)
+}
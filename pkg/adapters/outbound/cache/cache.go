@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/ports/output/identity"
+)
+
+const (
+	DefaultTTL        = time.Minute
+	DefaultMaxEntries = 1024
+)
+
+// credentialHeaders always vary the cache key, so that a response fetched on
+// behalf of one caller is never served to another. The correlation id is left
+// out since it changes with every operation.
+var credentialHeaders = []string{
+	"Authorization",
+	"X-Api-Key",
+	"Cookie",
+	identity.HeaderUserID,
+	identity.HeaderUserPool,
+	identity.HeaderTenant,
+	identity.HeaderRoles,
+}
+
+type (
+	// Cache keeps decoded-ready response bodies of idempotent reads. It is
+	// shared by the connector decorator and the transports' GET methods.
+	Cache struct {
+		backend     Backend
+		ttl         time.Duration
+		varyHeaders []string
+	}
+
+	Option func(*Cache)
+
+	// Directives holds the parts of a Cache-Control header relevant to a
+	// client side cache.
+	Directives struct {
+		NoStore bool
+		NoCache bool
+		MaxAge  *time.Duration
+	}
+)
+
+func New(opts ...Option) *Cache {
+	c := &Cache{ttl: DefaultTTL}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.backend == nil {
+		c.backend = NewMemoryBackend(DefaultMaxEntries)
+	}
+	return c
+}
+
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxEntries bounds the default in-memory backend. It has no effect when
+// a custom backend is configured.
+func WithMaxEntries(maxEntries int) Option {
+	return func(c *Cache) {
+		if c.backend == nil {
+			c.backend = NewMemoryBackend(maxEntries)
+		}
+	}
+}
+
+func WithBackend(backend Backend) Option {
+	return func(c *Cache) {
+		c.backend = backend
+	}
+}
+
+// WithVaryHeaders adds the given request headers to the cache key, on top of
+// the credential and identity headers which are always part of it.
+func WithVaryHeaders(headers ...string) Option {
+	return func(c *Cache) {
+		c.varyHeaders = append(c.varyHeaders, headers...)
+	}
+}
+
+// Key builds the cache key from the target host, the resource (query
+// parameters are sorted), the credential and identity headers, the configured
+// vary headers and those of vary. The result is hashed so that credentials
+// never reach the backend in clear.
+func (c *Cache) Key(host, resource string, headers map[string]string, vary ...string) string {
+	var b strings.Builder
+	b.WriteString(host)
+	b.WriteString("|")
+	b.WriteString(canonicalResource(resource))
+	for _, name := range slices.Concat(credentialHeaders, c.varyHeaders, vary) {
+		if name == "" {
+			continue
+		}
+		b.WriteString("|")
+		b.WriteString(strings.ToLower(name))
+		b.WriteString("=")
+		b.WriteString(headerValue(headers, name))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) Lookup(ctx context.Context, key string) (*Entry, bool) {
+	return c.backend.Get(ctx, key)
+}
+
+// Store saves body, of media type contentType, under key honouring the
+// Cache-Control directives of the response. Entries with no-cache are kept
+// only to be revalidated by ETag.
+func (c *Cache) Store(ctx context.Context, key string, body []byte, contentType, cacheControl, etag string) {
+	directives := ParseCacheControl(cacheControl)
+	if directives.NoStore {
+		return
+	}
+	now := time.Now()
+	entry := &Entry{
+		Body:        body,
+		ContentType: contentType,
+		ETag:        etag,
+		StoredAt:    now,
+		ExpiresAt:   now.Add(c.expiration(directives)),
+	}
+	if directives.NoCache {
+		if etag == "" {
+			return
+		}
+		entry.ExpiresAt = now
+	}
+	c.backend.Set(ctx, key, entry)
+}
+
+// Revalidated extends the lifetime of entry after the server answered 304.
+func (c *Cache) Revalidated(ctx context.Context, key string, entry *Entry, cacheControl string) {
+	c.Store(ctx, key, entry.Body, entry.ContentType, cacheControl, entry.ETag)
+}
+
+func (c *Cache) Invalidate(ctx context.Context, key string) {
+	c.backend.Delete(ctx, key)
+}
+
+func (c *Cache) expiration(directives Directives) time.Duration {
+	if directives.MaxAge != nil {
+		return *directives.MaxAge
+	}
+	return c.ttl
+}
+
+func ParseCacheControl(value string) Directives {
+	var directives Directives
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			directives.NoStore = true
+		case "no-cache":
+			directives.NoCache = true
+		case "max-age", "s-maxage":
+			if seconds, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil {
+				maxAge := time.Duration(seconds) * time.Second
+				directives.MaxAge = &maxAge
+			}
+		}
+	}
+	return directives
+}
+
+func canonicalResource(resource string) string {
+	path, query, found := strings.Cut(resource, "?")
+	if !found {
+		return path
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return resource
+	}
+	// Encode sorts by key
+	return path + "?" + values.Encode()
+}
+
+func headerValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+)
+
+type (
+	cachedCall[T any] struct {
+		inner connector.Call[T]
+		cache *Cache
+	}
+)
+
+// NewCall decorates a connector so that Find, List, Page, Cursor, Ids and Strings
+// issued with the GET method are served from cache. Mutations pass through.
+func NewCall[T any](inner connector.Call[T], cache *Cache) connector.Call[T] {
+	return cachedCall[T]{inner: inner, cache: cache}
+}
+
+func (c cachedCall[T]) Find(parameter connector.Parameter, response *T) error {
+	return cached(c.cache, "find", parameter, response, c.inner.Find)
+}
+
+func (c cachedCall[T]) List(parameter connector.Parameter, response *[]T) error {
+	return cached(c.cache, "list", parameter, response, c.inner.List)
+}
+
+func (c cachedCall[T]) Page(parameter connector.Parameter, response *connector.ListResponse[T]) error {
+	return cached(c.cache, "page", parameter, response, c.inner.Page)
+}
+
+// Cursor fails with connector.ErrCursorUnsupported when the decorated call is
+// not a connector.CursorCall.
+func (c cachedCall[T]) Cursor(parameter connector.Parameter, response *connector.CursorResponse[T]) error {
+	inner, ok := c.inner.(connector.CursorCall[T])
+	if !ok {
+		return fmt.Errorf("%w: %T", connector.ErrCursorUnsupported, c.inner)
+	}
+	return cached(c.cache, "cursor", parameter, response, inner.Cursor)
+}
+
+func (c cachedCall[T]) Ids(parameter connector.Parameter, response *[]int64) error {
+	return cached(c.cache, "ids", parameter, response, c.inner.Ids)
+}
+
+func (c cachedCall[T]) Strings(parameter connector.Parameter, response *[]string) error {
+	return cached(c.cache, "strings", parameter, response, c.inner.Strings)
+}
+
+func (c cachedCall[T]) Create(parameter connector.Parameter, response *T) error {
+	return c.inner.Create(parameter, response)
+}
+
+func (c cachedCall[T]) Update(parameter connector.Parameter, response *T) error {
+	return c.inner.Update(parameter, response)
+}
+
+func (c cachedCall[T]) Inative(parameter connector.Parameter, response *T) error {
+	return c.inner.Inative(parameter, response)
+}
+
+func cached[R any](cache *Cache, operation string, parameter connector.Parameter, response *R, next func(connector.Parameter, *R) error) error {
+	if method := strings.ToUpper(parameter.Method); method != "" && method != http.MethodGet {
+		return next(parameter, response)
+	}
+
+	ctx := context.Background()
+	key := cache.Key(parameter.Host, operation+":"+parameter.Resource, parameter.Headers)
+	if entry, ok := cache.Lookup(ctx, key); ok && entry.Fresh(time.Now()) {
+		if err := json.Unmarshal(entry.Body, response); err == nil {
+			return nil
+		}
+		cache.Invalidate(ctx, key)
+	}
+
+	if err := next(parameter, response); err != nil {
+		return err
+	}
+	if body, err := json.Marshal(response); err == nil {
+		cache.Store(ctx, key, body, codec.ContentTypeJSON, "", "")
+	}
+	return nil
+}
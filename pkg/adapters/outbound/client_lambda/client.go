@@ -3,57 +3,138 @@ package client_lambda
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/sirupsen/logrus"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+	"iter"
 )
 
 type (
 	LambdaClient[T any, R any] struct {
-		client *lambda.Client
+		client  *lambda.Client
+		options shared_kernel.Options
 	}
 
+	// LambdaFunctionError is returned when the function itself failed. Use
+	// lambda2.WithLogTail to have its execution log attached.
+	LambdaFunctionError = lambda2.FunctionError
+
 	LambdaProtocolClient[T any, R any] interface {
-		Invoke(ctx context.Context, lambdaName string, _body T) (*R, error)
+		// Invoke returns a nil response for Event and DryRun invocations, use
+		// lambda2.WithInvocation to get their request id.
+		Invoke(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) (*R, error)
+		// Send queues an Event invocation and returns once Lambda accepted it.
+		Send(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) (*lambda2.Invocation, error)
+		// DryRun checks that the function exists and may be invoked.
+		DryRun(ctx context.Context, lambdaName string, opts ...lambda2.InvokeOption) (*lambda2.Invocation, error)
+		// InvokeStream invokes a function with response streaming, which is not
+		// bound to the 6 MB payload limit. The caller must close the stream.
+		InvokeStream(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) (*Stream, error)
+		StreamItems(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) iter.Seq2[R, error]
 	}
 )
 
-func NewLambdaRestProxyClient[T any, R any](lambdaClient *lambda.Client) LambdaProtocolClient[T, R] {
+func NewLambdaRestProxyClient[T any, R any](lambdaClient *lambda.Client, opts ...shared_kernel.Option) LambdaProtocolClient[T, R] {
 	return &LambdaClient[T, R]{
-		client: lambdaClient,
+		client:  lambdaClient,
+		options: shared_kernel.NewOptions(opts...),
 	}
 }
 
-func (c *LambdaClient[T, R]) Invoke(ctx context.Context, lambdaName string, _body T) (*R, error) {
+func (c *LambdaClient[T, R]) Invoke(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) (*R, error) {
+	log := c.options.Logger.With("function", lambdaName)
+
 	payloadBytes, err := json.Marshal(_body)
 	if err != nil {
-		logrus.Warnf("Failed to marshal payload: %v", err)
+		log.WarnContext(ctx, "failed to marshal payload", "error", err)
+		return nil, err
+	}
+
+	config := lambda2.NewInvokeConfig(opts...)
+	resp, err := c.invoke(ctx, lambdaName, payloadBytes, config)
+	if err != nil || !config.IsSync() {
+		return nil, err
+	}
+
+	log.DebugContext(ctx, "received lambda response", "status_code", resp.StatusCode, "payload", c.options.Redactor.LogBody(resp.Payload))
+
+	var result R
+	convertErr := json.Unmarshal(resp.Payload, &result)
+	if convertErr != nil {
+		log.WarnContext(ctx, "failed to unmarshal lambda response", "error", convertErr)
+	}
+	return &result, convertErr
+}
+
+func (c *LambdaClient[T, R]) Send(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) (*lambda2.Invocation, error) {
+	payloadBytes, err := json.Marshal(_body)
+	if err != nil {
+		c.options.Logger.WarnContext(ctx, "failed to marshal payload", "function", lambdaName, "error", err)
+		return nil, err
+	}
+	config := lambda2.NewInvokeConfig(append(opts, lambda2.AsEvent())...)
+	resp, err := c.invoke(ctx, lambdaName, payloadBytes, config)
+	if err != nil {
 		return nil, err
 	}
+	invocation := lambda2.NewInvocation(resp)
+	return &invocation, nil
+}
+
+func (c *LambdaClient[T, R]) DryRun(ctx context.Context, lambdaName string, opts ...lambda2.InvokeOption) (*lambda2.Invocation, error) {
+	config := lambda2.NewInvokeConfig(append(opts, lambda2.AsDryRun())...)
+	resp, err := c.invoke(ctx, lambdaName, nil, config)
+	if err != nil {
+		return nil, err
+	}
+	invocation := lambda2.NewInvocation(resp)
+	return &invocation, nil
+}
 
-	resp, err := c.client.Invoke(ctx, &lambda.InvokeInput{
+func (c *LambdaClient[T, R]) invoke(ctx context.Context, lambdaName string, payload []byte, config lambda2.InvokeConfig) (*lambda.InvokeOutput, error) {
+	log := c.options.Logger.With("function", lambdaName, "invocation_type", config.InvocationType)
+
+	input := &lambda.InvokeInput{
 		FunctionName: aws.String(lambdaName),
-		Payload:      payloadBytes,
-	})
+		Payload:      payload,
+	}
+	if err := config.Apply(input); err != nil {
+		log.WarnContext(ctx, "failed to encode client context", "error", err)
+		return nil, err
+	}
+
+	log.DebugContext(ctx, "invoking lambda", "qualifier", config.Qualifier, "payload", c.options.Redactor.LogBody(payload))
 
+	limiter := c.options.Limiters.For(lambdaName)
+	release, err := limiter.Acquire(ctx)
 	if err != nil {
-		logrus.Warnf("Failed to invoke lambda %s: %v", lambdaName, err)
+		log.WarnContext(ctx, "lambda invocation not sent", "error", err)
+		return nil, err
+	}
+	resp, err := c.client.Invoke(ctx, input)
+	release()
+	limiter.Observe(err)
+	if err != nil {
+		log.WarnContext(ctx, "lambda invocation failed", "error", err)
 		return nil, err
 	}
 
-	if resp.FunctionError != nil {
-		logrus.Warnf("Failed to invoke lambda %s: %v", lambdaName, *resp.FunctionError)
-		return nil, errors.New(*resp.FunctionError)
+	invocation := config.Acknowledge(resp)
+	if invocation.LogTail != "" {
+		log.DebugContext(ctx, "lambda execution log", "request_id", invocation.RequestID, "log", invocation.LogTail)
 	}
 
-	logrus.Debugf("Lambda response status code: %d", resp.StatusCode)
-	logrus.Debugf("Lambda response payload: %s", string(resp.Payload))
+	if resp.FunctionError != nil {
+		functionError := lambda2.NewFunctionError(aws.ToString(resp.FunctionError), resp.Payload)
+		functionError.RequestID = invocation.RequestID
+		functionError.LogTail = invocation.LogTail
+		log.WarnContext(ctx, "lambda function error", "request_id", invocation.RequestID, "error", functionError)
+		return nil, functionError
+	}
 
-	var result R
-	convertErr := json.Unmarshal(resp.Payload, &result)
-	if convertErr != nil {
-		logrus.Warnf("Failed to unmarshal payload: %v", err)
+	if !config.IsSync() {
+		log.DebugContext(ctx, "lambda invocation accepted", "status_code", invocation.StatusCode, "request_id", invocation.RequestID)
 	}
-	return &result, convertErr
+	return resp, nil
 }
@@ -0,0 +1,173 @@
+package client_lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+)
+
+type (
+	// Stream is the response of a streaming invocation. Reading it returns
+	// the payload chunks in order, then io.EOF once the invocation completed,
+	// or a *lambda2.StreamError if it failed midway.
+	Stream struct {
+		ContentType string
+		Invocation  lambda2.Invocation
+
+		events  *lambda.InvokeWithResponseStreamEventStream
+		pending []byte
+		err     error
+		release func()
+	}
+)
+
+func (c *LambdaClient[T, R]) InvokeStream(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) (*Stream, error) {
+	log := c.options.Logger.With("function", lambdaName)
+
+	payloadBytes, err := json.Marshal(_body)
+	if err != nil {
+		log.WarnContext(ctx, "failed to marshal payload", "error", err)
+		return nil, err
+	}
+
+	config := lambda2.NewInvokeConfig(opts...)
+	if !config.IsSync() {
+		return nil, fmt.Errorf("invocation type %s can not stream its response", config.InvocationType)
+	}
+	input := &lambda.InvokeInput{}
+	if err := config.Apply(input); err != nil {
+		log.WarnContext(ctx, "failed to encode client context", "error", err)
+		return nil, err
+	}
+
+	log.DebugContext(ctx, "invoking lambda with response stream", "qualifier", config.Qualifier, "payload", c.options.Redactor.LogBody(payloadBytes))
+
+	limiter := c.options.Limiters.For(lambdaName)
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		log.WarnContext(ctx, "lambda invocation not sent", "error", err)
+		return nil, err
+	}
+	resp, err := c.client.InvokeWithResponseStream(ctx, &lambda.InvokeWithResponseStreamInput{
+		FunctionName:  aws.String(lambdaName),
+		Payload:       payloadBytes,
+		Qualifier:     input.Qualifier,
+		LogType:       input.LogType,
+		ClientContext: input.ClientContext,
+	})
+	limiter.Observe(err)
+	if err != nil {
+		release()
+		log.WarnContext(ctx, "lambda invocation failed", "error", err)
+		return nil, err
+	}
+
+	invocation := config.Acknowledge(&lambda.InvokeOutput{
+		StatusCode:      resp.StatusCode,
+		ExecutedVersion: resp.ExecutedVersion,
+		ResultMetadata:  resp.ResultMetadata,
+	})
+	return &Stream{
+		ContentType: aws.ToString(resp.ResponseStreamContentType),
+		Invocation:  invocation,
+		events:      resp.GetStream(),
+		release:     release,
+	}, nil
+}
+
+// StreamItems invokes the function and decodes its NDJSON response stream
+// into R values as they arrive. The stream is closed when iteration ends.
+func (c *LambdaClient[T, R]) StreamItems(ctx context.Context, lambdaName string, _body T, opts ...lambda2.InvokeOption) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		stream, err := c.InvokeStream(ctx, lambdaName, _body, opts...)
+		if err != nil {
+			var zero R
+			yield(zero, err)
+			return
+		}
+		defer stream.Close()
+		for item, err := range lambda2.DecodeNDJSON[R](stream) {
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.next()
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Chunks yields the payload chunks as Lambda sends them and closes the
+// stream when iteration ends.
+func (s *Stream) Chunks() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		defer s.Close()
+		for {
+			if len(s.pending) > 0 {
+				chunk := s.pending
+				s.pending = nil
+				if !yield(chunk, nil) {
+					return
+				}
+				continue
+			}
+			if s.err != nil {
+				if !errors.Is(s.err, io.EOF) {
+					yield(nil, s.err)
+				}
+				return
+			}
+			s.next()
+		}
+	}
+}
+
+// Close stops the stream and frees its concurrency slot.
+func (s *Stream) Close() error {
+	if s.release != nil {
+		s.release()
+		s.release = nil
+	}
+	return s.events.Close()
+}
+
+func (s *Stream) next() {
+	event, ok := <-s.events.Events()
+	if !ok {
+		s.err = s.events.Err()
+		if s.err == nil {
+			s.err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	switch e := event.(type) {
+	case *types.InvokeWithResponseStreamResponseEventMemberPayloadChunk:
+		s.pending = e.Value.Payload
+	case *types.InvokeWithResponseStreamResponseEventMemberInvokeComplete:
+		s.err = io.EOF
+		if e.Value.ErrorCode != nil {
+			s.err = &lambda2.StreamError{
+				ErrorCode:    aws.ToString(e.Value.ErrorCode),
+				ErrorDetails: aws.ToString(e.Value.ErrorDetails),
+				LogResult:    lambda2.DecodeLogResult(e.Value.LogResult),
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package client_lambda_proxy
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+	"github.com/tecmise/connector-lib/pkg/ports/output/logging"
+)
+
+// encodeEventBody encodes body like Options.EncodeRequest, reading streamed
+// bodies in full since the invoke payload can not be streamed.
+func encodeEventBody(options shared_kernel.Options, method string, body interface{}) (*shared_kernel.EncodedBody, error) {
+	encoded, err := options.EncodeRequest(method, body)
+	if err != nil || encoded == nil {
+		return encoded, err
+	}
+	return encoded, encoded.Buffer()
+}
+
+// setEventBody attaches encoded to event, base64 encoding binary bodies as
+// API Gateway does. The content type set by the caller is kept, except for
+// multipart bodies which must carry their own boundary.
+func setEventBody(event *lambda2.Event, encoded *shared_kernel.EncodedBody) {
+	if current := headerValue(event.Headers, "Content-Type"); current == "" || encoded.IsMultipart() || strings.HasPrefix(current, "multipart/") {
+		setHeader(event.Headers, "Content-Type", encoded.ContentType)
+	}
+	if encoded.Binary {
+		event.Body = base64.StdEncoding.EncodeToString(encoded.Data)
+		event.IsBase64Encoded = true
+		return
+	}
+	event.Body = string(encoded.Data)
+}
+
+// setHeader sets name in its canonical form, dropping the keys that only
+// differ from it in case.
+func setHeader(headers map[string]string, name, value string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+	headers[http.CanonicalHeaderKey(name)] = value
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// loggableBody returns the body as it should be logged, built only when the
+// line is emitted.
+func loggableBody(options shared_kernel.Options, encoded *shared_kernel.EncodedBody) logging.Lazy {
+	return func() string {
+		if encoded == nil {
+			return ""
+		}
+		if encoded.Binary {
+			return fmt.Sprintf("[%s, %d bytes]", encoded.ContentType, len(encoded.Data))
+		}
+		return options.Redactor.Body(encoded.Data)
+	}
+}
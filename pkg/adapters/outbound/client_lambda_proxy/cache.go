@@ -0,0 +1,21 @@
+package client_lambda_proxy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+)
+
+// storeInCache keeps the raw invoke payload of a successful proxy response,
+// which is JSON carrying the headers of the response itself, honouring the
+// Cache-Control header returned by the function.
+func storeInCache(ctx context.Context, options shared_kernel.Options, key string, payload []byte) {
+	result, err := lambda2.DecodeResponse(payload)
+	if err != nil || result.StatusCode != http.StatusOK {
+		return
+	}
+	options.Cache.Store(ctx, key, payload, codec.ContentTypeJSON, result.Header("Cache-Control"), "")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
 	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
 	"github.com/tecmise/connector-lib/pkg/ports/output/constant"
 	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
@@ -13,157 +14,146 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/sirupsen/logrus"
 )
 
 type (
-	payload struct {
-		Resource                        string                     `json:"resource"`
-		Path                            string                     `json:"path"`
-		HttpMethod                      string                     `json:"httpMethod"`
-		Headers                         lambda2.Headers            `json:"headers"`
-		MultiValueHeaders               lambda2.MultiValuesHeaders `json:"multiValueHeaders"`
-		QueryStringParameters           interface{}                `json:"queryStringParameters"`
-		MultiValueQueryStringParameters interface{}                `json:"multiValueQueryStringParameters"`
-		PathParameters                  interface{}                `json:"pathParameters"`
-		RequestContext                  lambda2.RequestContext     `json:"requestContext"`
-		Body                            string                     `json:"body"`
-	}
-
 	Connector[T any] struct {
 		parameter connector.Parameter
+		options   shared_kernel.Options
 	}
 )
 
 func (c Connector[T]) Find(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) List(parameter connector.Parameter, response *[]T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Page(parameter connector.Parameter, response *connector.ListResponse[T]) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
+}
+
+func (c Connector[T]) Cursor(parameter connector.Parameter, response *connector.CursorResponse[T]) error {
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Ids(parameter connector.Parameter, response *[]int64) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Strings(parameter connector.Parameter, response *[]string) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Create(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Update(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Inative(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
-func call(parameter connector.Parameter, response interface{}) error {
+func call(options shared_kernel.Options, parameter connector.Parameter, response interface{}) error {
+	log := options.Logger.With("function", parameter.Host, "method", parameter.Method, "resource", parameter.Resource)
+
 	client, ok := LambdaClients[parameter.Region]
 	if !ok {
-		logrus.Errorf("Região %s não definida em LambdaClients", parameter.Region)
+		log.Error("lambda client not configured for region", "region", parameter.Region)
 		return errors.New("region doesn't defined")
 	}
 
-	payloadBytes, err := json.Marshal(parameter.Body)
+	encoded, err := encodeEventBody(options, parameter.Method, parameter.Body)
 	if err != nil {
-		logrus.Errorf("Erro ao serializar o body do parâmetro: %v", err)
+		log.Error("failed to encode request body", "error", err)
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	var body string
-	if parameter.Method == "POST" || parameter.Method == "PUT" {
-		body = string(payloadBytes)
-	}
-
-	payloadData := payload{
-		Resource:   parameter.Resource,
-		Path:       parameter.Resource,
-		HttpMethod: parameter.Method,
-		Headers: lambda2.Headers{
-			Accept:      "application/json",
-			ContentType: "application/json",
-			XUserPool:   parameter.UserPoolID,
-		},
-		MultiValueHeaders: lambda2.MultiValuesHeaders{
-			Accept:      []string{"application/json"},
-			ContentType: []string{"application/json"},
-			XUserPool:   []string{parameter.UserPoolID},
-		},
-		PathParameters: nil,
-		RequestContext: lambda2.RequestContext{
-			ResourcePath: parameter.Resource,
-			Path:         parameter.Resource,
-			HttpMethod:   parameter.Method,
-		},
-		Body: body,
-	}
-
-	payloadJson, err := json.Marshal(payloadData)
+	headers, err := options.ApplyCredentials(context.TODO(), parameter.Headers)
 	if err != nil {
-		logrus.Errorf("Erro ao serializar o payload: %v", err)
+		log.Error("failed to resolve credentials", "error", err)
+		return err
+	}
+	setHeader(headers, "Accept", options.Codec.ContentType())
+	caller := parameter.ResolveIdentity(context.TODO())
+	headers = options.ApplyIdempotencyKey(context.TODO(), parameter.Method, caller.Apply(headers))
+
+	event := lambda2.NewEvent(parameter.Method, parameter.Resource)
+	if parameter.ResourceTemplate != "" {
+		event.SetResource(parameter.ResourceTemplate, parameter.PathParameters)
+	}
+	event.StageVariables = parameter.StageVariables
+	event.Headers = headers
+	if encoded != nil {
+		setEventBody(&event, encoded)
+	} else if headerValue(headers, "Content-Type") == "" {
+		setHeader(headers, "Content-Type", options.Codec.ContentType())
+	}
+	event.Stage = options.Stage
+	event.Authorizer = caller.Authorizer()
+
+	payloadJson, err := json.Marshal(event.Build(options.PayloadVersion))
+	if err != nil {
+		log.Error("failed to marshal proxy event", "error", err)
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	logrus.Debugf("Payload JSON: %s", string(payloadJson))
+	log.Debug("invoking lambda", "headers", options.Redactor.Headers(headers), "body", loggableBody(options, encoded))
 
 	input := &lambda.InvokeInput{
 		FunctionName: aws.String(parameter.Host),
 		Payload:      payloadJson,
 	}
 
-	resp, err := client.Invoke(context.TODO(), input)
+	resp, err := invokeLambda(context.TODO(), options, client, input, &event)
 	if err != nil {
-		logrus.Errorf("Falha ao invocar a Lambda: %v", err)
+		log.Error("lambda invocation failed", "error", err)
 		return fmt.Errorf("failed to invoke lambda: %w", err)
 	}
 
 	if resp.FunctionError != nil {
-		logrus.Errorf("Erro na função Lambda: %s", aws.ToString(resp.FunctionError))
-		return fmt.Errorf("lambda error: %s", aws.ToString(resp.FunctionError))
+		functionError := lambda2.NewFunctionError(aws.ToString(resp.FunctionError), resp.Payload)
+		log.Error("lambda function error", "error", functionError)
+		return functionError
 	}
 
-	logrus.Debugf("Lambda response status code: %d", resp.StatusCode)
-	logrus.Debugf("Lambda response payload: %s", string(resp.Payload))
+	log.Debug("received lambda response", "status_code", resp.StatusCode, "payload", options.Redactor.LogBody(resp.Payload))
 
 	if resp.StatusCode == 204 {
-		logrus.Debugf("Lambda retornou status code 204 (No Content)")
 		return nil
 	}
 
-	var result lambda2.Response
-	err = json.Unmarshal(resp.Payload, &result)
+	result, err := lambda2.DecodeResponse(resp.Payload)
 	if err != nil {
-		logrus.Errorf("Erro ao desserializar o payload de resposta da Lambda: %v", err)
+		log.Error("failed to unmarshal lambda response", "error", err)
 		return err
 	}
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		err = json.Unmarshal([]byte(result.Body), &response)
-		return nil
+	if result.StatusCode >= 200 && result.StatusCode < 300 {
+		if result.StatusCode == 204 || result.Body == "" {
+			return nil
+		}
+		return result.DecodeWith(response, options.Decode)
 	}
 
-	var errResponse connector.Result[string]
-	err = json.Unmarshal(resp.Payload, &errResponse)
+	body, err := result.BodyBytes()
 	if err != nil {
-		logrus.Errorf("Erro ao desserializar a resposta de erro da Lambda: %v", err)
 		return err
 	}
-	logrus.Errorf("Chamada à Lambda retornou erro: %s", errResponse.Content)
-	return errors.New(errResponse.Content)
+	err = options.DecodeError(result.StatusCode, body)
+	log.Error("lambda returned an error", "status_code", result.StatusCode, "error", err)
+	return err
 }
 
-func NewConnector[T any]() connector.Call[T] {
-	return Connector[T]{}
+func NewConnector[T any](opts ...shared_kernel.Option) connector.Call[T] {
+	return Connector[T]{
+		options: shared_kernel.NewOptions(opts...),
+	}
 }
 
 var (
@@ -3,12 +3,16 @@ package client_lambda_proxy
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/sirupsen/logrus"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+	"github.com/tecmise/connector-lib/pkg/ports/output/identity"
 	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type (
@@ -16,133 +20,169 @@ type (
 		lambdaName string
 		uri        string
 		client     *lambda.Client
+		options    shared_kernel.Options
 	}
 
 	LambdaProxyProtocolClient[T any, R any] interface {
-		GET(ctx context.Context) lambda2.InvokeOutputResult[R]
-		POST(ctx context.Context, body *T) lambda2.InvokeOutputResult[R]
-		PUT(ctx context.Context, body *T) lambda2.InvokeOutputResult[R]
-		PATCH(ctx context.Context, body *T) lambda2.InvokeOutputResult[R]
-		DELETE(ctx context.Context, body *T) lambda2.InvokeOutputResult[R]
+		GET(ctx context.Context, opts ...lambda2.InvokeOption) (*R, error)
+		POST(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error)
+		PUT(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error)
+		PATCH(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error)
+		DELETE(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error)
+		Invoke(ctx context.Context, method string, body *T, opts ...lambda2.InvokeOption) (*lambda2.ProxyResult[R], error)
 	}
 )
 
-func NewClient[T any, R any](lambdaClient *lambda.Client, lambdaName string, uri string) LambdaProxyProtocolClient[T, R] {
+func NewClient[T any, R any](lambdaClient *lambda.Client, lambdaName string, uri string, opts ...shared_kernel.Option) LambdaProxyProtocolClient[T, R] {
 	return &protocolClient[T, R]{
 		lambdaName: lambdaName,
 		client:     lambdaClient,
 		uri:        uri,
+		options:    shared_kernel.NewOptions(opts...),
 	}
 }
 
-func (c *protocolClient[T, R]) GET(ctx context.Context) lambda2.InvokeOutputResult[R] {
-	return c.invoke(ctx, nil, http.MethodGet)
+func (c *protocolClient[T, R]) GET(ctx context.Context, opts ...lambda2.InvokeOption) (*R, error) {
+	return c.body(c.invoke(ctx, nil, http.MethodGet, opts))
 }
 
-func (c *protocolClient[T, R]) POST(ctx context.Context, body *T) lambda2.InvokeOutputResult[R] {
-	return c.invoke(ctx, body, http.MethodPost)
+func (c *protocolClient[T, R]) POST(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error) {
+	return c.body(c.invoke(ctx, body, http.MethodPost, opts))
 }
 
-func (c *protocolClient[T, R]) PUT(ctx context.Context, body *T) lambda2.InvokeOutputResult[R] {
-	return c.invoke(ctx, body, http.MethodPut)
+func (c *protocolClient[T, R]) PUT(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error) {
+	return c.body(c.invoke(ctx, body, http.MethodPut, opts))
 }
 
-func (c *protocolClient[T, R]) PATCH(ctx context.Context, body *T) lambda2.InvokeOutputResult[R] {
-	return c.invoke(ctx, body, http.MethodPatch)
+func (c *protocolClient[T, R]) PATCH(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error) {
+	return c.body(c.invoke(ctx, body, http.MethodPatch, opts))
 }
 
-func (c *protocolClient[T, R]) DELETE(ctx context.Context, body *T) lambda2.InvokeOutputResult[R] {
-	return c.invoke(ctx, body, http.MethodDelete)
+func (c *protocolClient[T, R]) DELETE(ctx context.Context, body *T, opts ...lambda2.InvokeOption) (*R, error) {
+	return c.body(c.invoke(ctx, body, http.MethodDelete, opts))
+}
+
+// Invoke sends body with method and returns the whole decoded response, for
+// callers that need the status code or headers.
+func (c *protocolClient[T, R]) Invoke(ctx context.Context, method string, body *T, opts ...lambda2.InvokeOption) (*lambda2.ProxyResult[R], error) {
+	if body == nil {
+		return c.invoke(ctx, nil, strings.ToUpper(method), opts)
+	}
+	return c.invoke(ctx, body, strings.ToUpper(method), opts)
+}
+
+func (c *protocolClient[T, R]) body(result *lambda2.ProxyResult[R], err error) (*R, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result.Body, nil
 }
 
 func (c *protocolClient[T, R]) invoke(
 	ctx context.Context,
 	_body interface{},
 	method string,
-) lambda2.InvokeOutputResult[R] {
-	payloadBytes, err := json.Marshal(_body)
+	opts []lambda2.InvokeOption,
+) (*lambda2.ProxyResult[R], error) {
+	config := lambda2.NewInvokeConfig(opts...)
+	log := c.options.Logger.With("function", c.lambdaName, "method", method, "resource", c.uri)
+
+	encoded, err := encodeEventBody(c.options, method, _body)
 	if err != nil {
-		logrus.Errorf("Erro ao serializar o body do parâmetro: %v", err)
-		return lambda2.InvokeOutputResult[R]{
-			Output: nil,
-			Error:  err,
-		}
+		log.ErrorContext(ctx, "failed to encode request body", "error", err)
+		return nil, err
 	}
 
-	var body string
-	if method == "POST" || method == "PUT" {
-		body = string(payloadBytes)
+	headers, err := c.options.ApplyCredentials(ctx, nil)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to resolve credentials", "error", err)
+		return nil, err
 	}
 
-	token := ctx.Value("bearer-token")
-	xApiKey := ctx.Value("x-api-key")
-	headers := make(map[string]string)
+	setHeader(headers, "Accept", c.options.Codec.ContentType())
+	caller, _ := identity.FromContext(ctx)
+	headers = c.options.ApplyIdempotencyKey(ctx, method, caller.Apply(headers))
 
-	if token == nil {
-		logrus.Warnf("Token is null in context!")
-	} else {
-		headers["Authorization"] = fmt.Sprintf("Bearer %s", token.(string))
+	resource := c.uri
+	if cursor, ok := connector.CursorFromContext(ctx); ok {
+		resource, headers = c.options.CursorStrategy.Apply(resource, headers, cursor)
 	}
 
-	if xApiKey == nil {
-		logrus.Warnf("X api key is null!")
-	} else {
-		headers["x-api-key"] = xApiKey.(string)
+	event := lambda2.NewEvent(method, resource)
+	config.ApplyEvent(&event)
+	event.Headers = headers
+
+	var cacheKey string
+	if c.options.Cache != nil && method == http.MethodGet && config.IsSync() && config.Qualifier == "" {
+		cacheKey = c.options.Cache.Key(c.lambdaName, event.Path+"?"+event.Query.Encode(), headers, c.options.CursorStrategy.Header())
+		if entry, ok := c.options.Cache.Lookup(ctx, cacheKey); ok && entry.Fresh(time.Now()) {
+			log.DebugContext(ctx, "lambda response served from cache")
+			return lambda2.DecodeResult[R](entry.Body, c.options.Decode)
+		}
 	}
 
-	payloadData := lambda2.Payload{
-		Resource:          c.uri,
-		Path:              c.uri,
-		HttpMethod:        method,
-		Headers:           headers,
-		MultiValueHeaders: map[string][]string{},
-		PathParameters:    nil,
-		RequestContext: lambda2.RequestContext{
-			ResourcePath: c.uri,
-			Path:         c.uri,
-			HttpMethod:   method,
-		},
-		Body: body,
+	if encoded != nil {
+		setEventBody(&event, encoded)
 	}
+	event.Stage = c.options.Stage
+	event.Authorizer = caller.Authorizer()
 
-	payloadJson, err := json.Marshal(payloadData)
+	payloadJson, err := json.Marshal(event.Build(c.options.PayloadVersion))
 	if err != nil {
-		logrus.Errorf("Erro ao serializar o payload: %v", err)
-		return lambda2.InvokeOutputResult[R]{
-			Output: nil,
-			Error:  err,
-		}
+		log.ErrorContext(ctx, "failed to marshal proxy event", "error", err)
+		return nil, err
 	}
 
-	logrus.Debugf("Payload JSON: %s", string(payloadJson))
+	log.DebugContext(ctx, "invoking lambda", "headers", c.options.Redactor.Headers(headers), "body", loggableBody(c.options, encoded))
 
 	input := &lambda.InvokeInput{
 		FunctionName: aws.String(c.lambdaName),
 		Payload:      payloadJson,
 	}
+	if err := config.Apply(input); err != nil {
+		log.ErrorContext(ctx, "failed to encode client context", "error", err)
+		return nil, err
+	}
 
-	resp, err := c.client.Invoke(context.TODO(), input)
+	resp, err := invokeLambda(ctx, c.options, c.client, input, &event)
 	if err != nil {
-		logrus.Errorf("Falha ao invocar a Lambda: %v", err)
-		return lambda2.InvokeOutputResult[R]{
-			Output: nil,
-			Error:  err,
-		}
+		log.ErrorContext(ctx, "lambda invocation failed", "error", err)
+		return nil, err
+	}
+
+	invocation := config.Acknowledge(resp)
+	if invocation.LogTail != "" {
+		log.DebugContext(ctx, "lambda execution log", "request_id", invocation.RequestID, "log", invocation.LogTail)
 	}
 
 	if resp.FunctionError != nil {
-		logrus.Errorf("Erro na função Lambda: %s", aws.ToString(resp.FunctionError))
-		return lambda2.InvokeOutputResult[R]{
-			Output: nil,
-			Error:  err,
-		}
+		functionError := lambda2.NewFunctionError(aws.ToString(resp.FunctionError), resp.Payload)
+		functionError.RequestID = invocation.RequestID
+		functionError.LogTail = invocation.LogTail
+		log.ErrorContext(ctx, "lambda function error", "request_id", invocation.RequestID, "error", functionError)
+		return nil, functionError
 	}
 
-	logrus.Debugf("Lambda response status code: %d", resp.StatusCode)
-	logrus.Debugf("Lambda response payload: %s", string(resp.Payload))
+	if !config.IsSync() {
+		log.DebugContext(ctx, "lambda invocation accepted", "status_code", invocation.StatusCode, "request_id", invocation.RequestID)
+		return &lambda2.ProxyResult[R]{StatusCode: int(resp.StatusCode), Invocation: invocation}, nil
+	}
+
+	log.DebugContext(ctx, "received lambda response", "status_code", resp.StatusCode, "payload", c.options.Redactor.LogBody(resp.Payload))
+
+	if cacheKey != "" {
+		storeInCache(ctx, c.options, cacheKey, resp.Payload)
+	}
 
-	return lambda2.InvokeOutputResult[R]{
-		Output: resp,
-		Error:  nil,
+	result, err := lambda2.DecodeResult[R](resp.Payload, c.options.Decode)
+	var statusError *lambda2.StatusError
+	if errors.As(err, &statusError) {
+		statusError.Message = c.options.DecodeError(statusError.StatusCode, statusError.Body).Error()
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "lambda proxy request failed", "error", err)
+		return nil, err
 	}
+	result.Invocation = invocation
+	return result, nil
 }
@@ -0,0 +1,46 @@
+package client_lambda_proxy
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+)
+
+// Pages adapts a function to connector.PageFetcher, uri returns the path
+// (with its page query parameter) of a given page.
+func Pages[T any](lambdaClient *lambda.Client, lambdaName string, uri func(page int) string, opts ...shared_kernel.Option) connector.PageFetcher[T] {
+	options := shared_kernel.NewOptions(opts...)
+	return func(ctx context.Context, page int) (*connector.ListResponse[T], error) {
+		client := &protocolClient[any, connector.ListResponse[T]]{
+			lambdaName: lambdaName,
+			client:     lambdaClient,
+			uri:        uri(page),
+			options:    options,
+		}
+		return emptyIfNil(client.GET(ctx))
+	}
+}
+
+// Cursors adapts a function to connector.CursorFetcher. The cursor is sent
+// according to the cursor strategy of opts.
+func Cursors[T any](lambdaClient *lambda.Client, lambdaName string, uri string, opts ...shared_kernel.Option) connector.CursorFetcher[T] {
+	client := &protocolClient[any, connector.CursorResponse[T]]{
+		lambdaName: lambdaName,
+		client:     lambdaClient,
+		uri:        uri,
+		options:    shared_kernel.NewOptions(opts...),
+	}
+	return func(ctx context.Context, cursor string) (*connector.CursorResponse[T], error) {
+		return emptyIfNil(client.GET(connector.WithCursor(ctx, cursor)))
+	}
+}
+
+// emptyIfNil turns the nil body of a 204 response into an empty page.
+func emptyIfNil[R any](response *R, err error) (*R, error) {
+	if err != nil || response != nil {
+		return response, err
+	}
+	return new(R), nil
+}
@@ -0,0 +1,140 @@
+package client_lambda_proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/hedge"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/ratelimit"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/retry"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+)
+
+// invokeLambda invokes the function, sharing one invocation among concurrent
+// identical synchronous GETs when the client has a coalescing group. Each
+// caller receives its own copy of the output. Synchronous GETs are hedged when
+// the client has a hedging policy. Throttled invocations are retried, mutating
+// ones only when event carries an idempotency key.
+func invokeLambda(ctx context.Context, options shared_kernel.Options, client *lambda.Client, input *lambda.InvokeInput, event *lambda2.Event) (*lambda.InvokeOutput, error) {
+	synchronous := input.InvocationType == "" || input.InvocationType == types.InvocationTypeRequestResponse
+	if event.Method != http.MethodGet || !synchronous {
+		if idempotency.Mutating(event.Method) && idempotency.Of(event.Headers) == "" {
+			return invokeLimited(ctx, options, client, input)
+		}
+		return invokeRetried(ctx, options, input, func(ctx context.Context) (*lambda.InvokeOutput, error) {
+			return invokeLimited(ctx, options, client, input)
+		})
+	}
+	if options.Coalescer == nil {
+		return invokeRetried(ctx, options, input, func(ctx context.Context) (*lambda.InvokeOutput, error) {
+			return invokeHedged(ctx, options, client, input)
+		})
+	}
+
+	value, coalesced, err := options.Coalescer.Do(ctx, eventKey(options, input, event), func() (interface{}, error) {
+		return invokeRetried(context.WithoutCancel(ctx), options, input, func(ctx context.Context) (*lambda.InvokeOutput, error) {
+			return invokeHedged(ctx, options, client, input)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if coalesced {
+		options.Logger.DebugContext(ctx, "lambda invocation coalesced", "function", aws.ToString(input.FunctionName))
+	}
+
+	output := *value.(*lambda.InvokeOutput)
+	output.Payload = append([]byte(nil), output.Payload...)
+	return &output, nil
+}
+
+// eventKey identifies an invocation by function, qualifier, client context,
+// payload version and the request described by event. The payload itself
+// can not be used, every event carries its own request id and time.
+func eventKey(options shared_kernel.Options, input *lambda.InvokeInput, event *lambda2.Event) string {
+	hash := sha256.New()
+	hash.Write([]byte(aws.ToString(input.FunctionName) + ":" + aws.ToString(input.Qualifier) + "\n"))
+	hash.Write([]byte(aws.ToString(input.ClientContext) + "\n"))
+	hash.Write([]byte(string(options.PayloadVersion) + " " + event.Stage + "\n"))
+	hash.Write([]byte(event.Method + " " + event.Resource + " " + event.Path + "?" + event.Query.Encode() + "\n"))
+	hash.Write([]byte(sortedPairs(event.PathParameters) + "\n"))
+	hash.Write([]byte(sortedPairs(event.StageVariables) + "\n"))
+	hash.Write([]byte(sortedPairs(event.Headers) + "\n"))
+	hash.Write([]byte(event.Body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func sortedPairs(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for key, value := range values {
+		pairs = append(pairs, strings.ToLower(key)+":"+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\n")
+}
+
+// invokeRetried calls invoke again while the function is throttled, as long
+// as the retry policy of the client allows. The payload, and so the
+// idempotency key of the event, is the same on every attempt.
+func invokeRetried(ctx context.Context, options shared_kernel.Options, input *lambda.InvokeInput, invoke func(ctx context.Context) (*lambda.InvokeOutput, error)) (*lambda.InvokeOutput, error) {
+	if options.Retry == nil {
+		return invoke(ctx)
+	}
+
+	attempt := 0
+	return retry.Do(ctx, options.Retry, func(ctx context.Context) (*lambda.InvokeOutput, error) {
+		if attempt++; attempt > 1 {
+			options.Logger.DebugContext(ctx, "retrying lambda invocation", "function", aws.ToString(input.FunctionName), "attempt", attempt)
+		}
+		return invoke(ctx)
+	}, throttled)
+}
+
+func throttled(_ *lambda.InvokeOutput, err error) (bool, time.Duration) {
+	delay, ok := ratelimit.ThrottleDelay(err)
+	return ok, delay
+}
+
+// invokeHedged invokes the function a second time when the first invocation
+// is slower than the hedging policy allows, cancelling the slower one.
+func invokeHedged(ctx context.Context, options shared_kernel.Options, client *lambda.Client, input *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	if options.Hedge == nil {
+		return invokeLimited(ctx, options, client, input)
+	}
+
+	var attempts atomic.Int32
+	return hedge.Do(ctx, options.Hedge, func(ctx context.Context) (*lambda.InvokeOutput, error) {
+		if attempts.Add(1) > 1 {
+			options.Logger.DebugContext(ctx, "hedging lambda invocation", "function", aws.ToString(input.FunctionName))
+		}
+		attempt := *input
+		return invokeLimited(ctx, options, client, &attempt)
+	})
+}
+
+// invokeLimited invokes the function within the limits configured for it,
+// reporting throttling back to the limiter.
+func invokeLimited(ctx context.Context, options shared_kernel.Options, client *lambda.Client, input *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	limiter := options.Limiters.For(aws.ToString(input.FunctionName))
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		options.Logger.WarnContext(ctx, "lambda invocation not sent", "function", aws.ToString(input.FunctionName), "error", err)
+		return nil, err
+	}
+	defer release()
+
+	output, err := client.Invoke(ctx, input)
+	limiter.Observe(err)
+	return output, err
+}
@@ -0,0 +1,56 @@
+package client_lambda_proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/coalesce"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+)
+
+func TestConcurrentIdenticalGetsInvokeOnce(t *testing.T) {
+	var invocations atomic.Int32
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invocations.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"statusCode":200,"headers":{"Content-Type":"application/json"},"body":"{\"name\":\"a\"}"}`))
+	}))
+	defer fake.Close()
+
+	lambdaClient := lambda.New(lambda.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(fake.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		RetryMaxAttempts: 1,
+	})
+	client := NewClient[struct{}, map[string]string](lambdaClient, "items-function", "items/1", shared_kernel.WithCoalescing(coalesce.NewGroup()))
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := client.GET(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if (*response)["name"] != "a" {
+				t.Errorf("response = %v", *response)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := invocations.Load(); n != 1 {
+		t.Fatalf("lambda invoked %d times, want 1", n)
+	}
+}
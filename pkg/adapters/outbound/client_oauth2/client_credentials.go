@@ -0,0 +1,246 @@
+package client_oauth2
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/coalesce"
+	"github.com/tecmise/connector-lib/pkg/ports/output/credentials"
+	"github.com/tecmise/connector-lib/pkg/ports/output/logging"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	DefaultExpiryDelta  = 30 * time.Second
+	DefaultRefreshAhead = 2 * time.Minute
+	DefaultTimeout      = 10 * time.Second
+
+	refreshRetryDelay = 5 * time.Second
+	// minRefreshDelay keeps tokens living shorter than RefreshAhead from
+	// being renewed in a loop
+	minRefreshDelay = time.Second
+)
+
+type (
+	Config struct {
+		TokenURL     string
+		ClientID     string
+		ClientSecret string
+		Scopes       []string
+		// ExpiryDelta is how long before expires_in a cached token stops
+		// being handed out.
+		ExpiryDelta time.Duration
+		// RefreshAhead is how long before expires_in the token is renewed in
+		// the background, so callers never wait for the token endpoint.
+		RefreshAhead time.Duration
+		// DisableBackgroundRefresh renews tokens only when a caller needs one.
+		DisableBackgroundRefresh bool
+		Timeout                  time.Duration
+		HTTPClient               *fasthttp.Client
+		Logger                   *slog.Logger
+	}
+
+	Token struct {
+		AccessToken string    `json:"access_token"`
+		TokenType   string    `json:"token_type"`
+		ExpiresIn   int64     `json:"expires_in"`
+		Expiry      time.Time `json:"-"`
+	}
+
+	// ClientCredentials issues machine tokens with the OAuth2 client
+	// credentials grant. Tokens are cached, renewed ahead of their expiry in
+	// the background, and concurrent renewals share a single request. It
+	// implements both credentials.Provider and credentials.TokenSource.
+	ClientCredentials struct {
+		config Config
+		group  *coalesce.Group
+
+		mu     sync.RWMutex
+		token  *Token
+		timer  *time.Timer
+		closed bool
+	}
+
+	tokenError struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+)
+
+func NewClientCredentials(config Config) *ClientCredentials {
+	if config.ExpiryDelta == 0 {
+		config.ExpiryDelta = DefaultExpiryDelta
+	}
+	if config.RefreshAhead == 0 {
+		config.RefreshAhead = DefaultRefreshAhead
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &fasthttp.Client{}
+	}
+	if config.Logger == nil {
+		config.Logger = logging.Default()
+	}
+	return &ClientCredentials{config: config, group: coalesce.NewGroup()}
+}
+
+func (c *ClientCredentials) Credentials(ctx context.Context) (credentials.Credentials, error) {
+	token, err := c.AccessToken(ctx)
+	if err != nil {
+		return credentials.Credentials{}, err
+	}
+	return credentials.Credentials{BearerToken: token}, nil
+}
+
+func (c *ClientCredentials) AccessToken(ctx context.Context) (string, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+
+	if token != nil && c.usable(token) {
+		return token, nil
+	}
+	return c.refresh(ctx)
+}
+
+// Close stops the background refresh.
+func (c *ClientCredentials) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+func (c *ClientCredentials) refresh(ctx context.Context) (*Token, error) {
+	value, _, err := c.group.Do(ctx, "token", func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.config.Timeout)
+		defer cancel()
+
+		token, err := fetchToken(ctx, c.config)
+		if err != nil {
+			return nil, err
+		}
+		c.store(token)
+		return token, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*Token), nil
+}
+
+func (c *ClientCredentials) store(token *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+	if !token.Expiry.IsZero() {
+		ahead := min(c.config.RefreshAhead, token.lifetime()/2)
+		c.schedule(max(time.Until(token.Expiry.Add(-ahead)), minRefreshDelay))
+	}
+}
+
+// schedule must be called with the lock held.
+func (c *ClientCredentials) schedule(delay time.Duration) {
+	if c.config.DisableBackgroundRefresh || c.closed {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(delay, c.backgroundRefresh)
+}
+
+func (c *ClientCredentials) backgroundRefresh() {
+	if _, err := c.refresh(context.Background()); err != nil {
+		c.config.Logger.Warn("oauth2 background token refresh failed", "token_url", c.config.TokenURL, "error", err)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.token != nil && c.usable(c.token) {
+			c.schedule(refreshRetryDelay)
+		}
+	}
+}
+
+func (c *ClientCredentials) usable(token *Token) bool {
+	if token.Expiry.IsZero() {
+		return true
+	}
+	delta := min(c.config.ExpiryDelta, token.lifetime()/4)
+	return time.Now().Add(delta).Before(token.Expiry)
+}
+
+// lifetime is the validity the token endpoint granted to the token.
+func (t *Token) lifetime() time.Duration {
+	return time.Duration(t.ExpiresIn) * time.Second
+}
+
+func fetchToken(ctx context.Context, config Config) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(config.TokenURL)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	basic := url.QueryEscape(config.ClientID) + ":" + url.QueryEscape(config.ClientSecret)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basic)))
+	req.SetBodyString(form.Encode())
+
+	var err error
+	if deadline, ok := ctx.Deadline(); ok {
+		err = config.HTTPClient.DoDeadline(req, resp, deadline)
+	} else {
+		err = config.HTTPClient.Do(req, resp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("requesting oauth2 token: %w", err)
+	}
+
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		var body tokenError
+		_ = json.Unmarshal(resp.Body(), &body)
+		return nil, fmt.Errorf("oauth2 token endpoint returned %d: %s %s", resp.StatusCode(), body.Error, body.ErrorDescription)
+	}
+
+	var token Token
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return nil, fmt.Errorf("decoding oauth2 token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2 token endpoint returned no access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
@@ -0,0 +1,136 @@
+package client_oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type tokenServer struct {
+	*httptest.Server
+	hits      atomic.Int32
+	expiresIn int
+	delay     time.Duration
+	status    atomic.Int32
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	s := &tokenServer{expiresIn: expiresIn}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit := s.hits.Add(1)
+		if r.FormValue("grant_type") != "client_credentials" {
+			t.Errorf("grant_type = %q", r.FormValue("grant_type"))
+		}
+		time.Sleep(s.delay)
+		if status := int(s.status.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad secret"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, hit, s.expiresIn)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *tokenServer) client(t *testing.T) *ClientCredentials {
+	c := NewClientCredentials(Config{TokenURL: s.URL, ClientID: "id", ClientSecret: "secret"})
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestClientCredentialsShortLifetime(t *testing.T) {
+	// expires_in is below the default RefreshAhead of two minutes
+	server := newTokenServer(t, 60)
+	c := server.client(t)
+
+	token, err := c.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Fatalf("access token = %q", token.AccessToken)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	if hits := server.hits.Load(); hits != 1 {
+		t.Fatalf("token endpoint hit %d times, want 1", hits)
+	}
+	if token, _ := c.Token(context.Background()); token.AccessToken != "token-1" {
+		t.Fatalf("cached token not reused, got %q", token.AccessToken)
+	}
+}
+
+func TestClientCredentialsRefreshesBeforeExpiry(t *testing.T) {
+	server := newTokenServer(t, 2)
+	c := server.client(t)
+
+	if _, err := c.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// renewed half way through the two seconds of validity
+	time.Sleep(1500 * time.Millisecond)
+	if hits := server.hits.Load(); hits != 2 {
+		t.Fatalf("token endpoint hit %d times, want 2", hits)
+	}
+}
+
+func TestClientCredentialsConcurrentCallers(t *testing.T) {
+	server := newTokenServer(t, 3600)
+	server.delay = 50 * time.Millisecond
+	c := server.client(t)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 50)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := c.AccessToken(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			tokens[i] = token
+		}()
+	}
+	wg.Wait()
+
+	if hits := server.hits.Load(); hits != 1 {
+		t.Fatalf("token endpoint hit %d times, want 1", hits)
+	}
+	for _, token := range tokens {
+		if token != "token-1" {
+			t.Fatalf("caller received %q", token)
+		}
+	}
+}
+
+func TestClientCredentialsRefreshFailure(t *testing.T) {
+	server := newTokenServer(t, 3600)
+	server.status.Store(http.StatusUnauthorized)
+	c := server.client(t)
+
+	_, err := c.Token(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("error = %v", err)
+	}
+
+	// the failure is not cached
+	server.status.Store(http.StatusOK)
+	token, err := c.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "token-2" {
+		t.Fatalf("access token = %q", token.AccessToken)
+	}
+}
@@ -0,0 +1,49 @@
+package client_rest
+
+import (
+	"strings"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/valyala/fasthttp"
+)
+
+// setBody attaches encoded to req. The content type set by the caller is
+// kept, except for multipart bodies which must carry their own boundary.
+func setBody(req *fasthttp.Request, encoded *shared_kernel.EncodedBody) {
+	current := string(req.Header.ContentType())
+	if encoded == nil {
+		if current == "" {
+			req.Header.SetContentType(shared_kernel.ContentTypeJSON)
+		}
+		return
+	}
+
+	if encoded.Stream != nil {
+		// fasthttp closes the stream once sent or when req is released
+		size := int(encoded.Size)
+		if size <= 0 {
+			size = -1
+		}
+		req.SetBodyStream(encoded.Stream, size)
+	} else {
+		req.SetBody(encoded.Data)
+	}
+	if current == "" || encoded.IsMultipart() || strings.HasPrefix(current, "multipart/") {
+		req.Header.SetContentType(encoded.ContentType)
+	}
+}
+
+// compressBody compresses the body of req when the client asks for request
+// compression and the body is large enough. Streams are sent as they are.
+func compressBody(options shared_kernel.Options, req *fasthttp.Request) error {
+	if req.IsBodyStream() || len(req.Header.ContentEncoding()) > 0 || !options.RequestCompression.Applies(len(req.Body())) {
+		return nil
+	}
+	compressed, err := shared_kernel.Compress(options.RequestCompression.Encoding, req.Body())
+	if err != nil {
+		return err
+	}
+	req.SetBodyRaw(compressed)
+	req.Header.SetContentEncoding(options.RequestCompression.Encoding)
+	return nil
+}
@@ -0,0 +1,52 @@
+package client_rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/cache"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+)
+
+func sendCachedRequest(ctx context.Context, options shared_kernel.Options, param requestObject, response interface{}) error {
+	key := options.Cache.Key(param.Host, param.Resource, param.Headers, options.CursorStrategy.Header())
+	entry, found := options.Cache.Lookup(ctx, key)
+	if found && entry.Fresh(time.Now()) {
+		options.Logger.DebugContext(ctx, "rest response served from cache", "resource", param.Resource)
+		return decodeResponse(options, cachedResponse(entry), response)
+	}
+
+	if found && entry.ETag != "" {
+		headers := make(map[string]string, len(param.Headers)+1)
+		for k, v := range param.Headers {
+			headers[k] = v
+		}
+		headers["If-None-Match"] = entry.ETag
+		param.Headers = headers
+	}
+
+	resp, err := doRequest(ctx, options, param)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.statusCode == http.StatusNotModified && found:
+		options.Cache.Revalidated(ctx, key, entry, resp.header.Get("Cache-Control"))
+		return decodeResponse(options, cachedResponse(entry), response)
+	case resp.statusCode == http.StatusOK:
+		options.Cache.Store(ctx, key, resp.body, resp.header.Get("Content-Type"), resp.header.Get("Cache-Control"), resp.header.Get("ETag"))
+	}
+	return decodeResponse(options, resp, response)
+}
+
+// cachedResponse rebuilds the response an entry was stored from, with the
+// content type its codec is negotiated from.
+func cachedResponse(entry *cache.Entry) *rawResponse {
+	header := http.Header{}
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+	return &rawResponse{statusCode: http.StatusOK, header: header, body: entry.Body}
+}
@@ -0,0 +1,73 @@
+package client_rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/cache"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+)
+
+func TestCacheKeepsOneEntryPerCaller(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"owner":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient[struct{}, map[string]string]("", shared_kernel.WithBaseURL(server.URL), shared_kernel.WithCache(cache.New()))
+	get := func(token string) string {
+		response, err := client.GET(context.Background(), "me", map[string]string{"Authorization": token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return (*response)["owner"]
+	}
+
+	if owner := get("Bearer a"); owner != "Bearer a" {
+		t.Fatalf("caller a received the response of %q", owner)
+	}
+	if owner := get("Bearer b"); owner != "Bearer b" {
+		t.Fatalf("caller b received the response of %q", owner)
+	}
+	if owner := get("Bearer a"); owner != "Bearer a" {
+		t.Fatalf("caller a received the response of %q", owner)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("server hit %d times, want one per caller", n)
+	}
+}
+
+func TestCacheHitKeepsTheContentType(t *testing.T) {
+	body, err := codec.MsgPack.Marshal(map[string]string{"name": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", codec.ContentTypeMsgPack)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient[struct{}, map[string]string]("", shared_kernel.WithBaseURL(server.URL), shared_kernel.WithCache(cache.New()))
+	for i := range 2 {
+		response, err := client.GET(context.Background(), "items/1", nil)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if (*response)["name"] != "a" {
+			t.Fatalf("call %d decoded %v", i, *response)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("server hit %d times, want 1", n)
+	}
+}
@@ -1,67 +1,73 @@
 package client_rest
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/envelope"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
 	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
-	"io"
-	"mime/multipart"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
 	"strings"
 
-	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 )
 
 type (
 	Connector[T any] struct {
 		parameter connector.Parameter
+		options   shared_kernel.Options
 	}
 )
 
-func NewConnector[T any]() connector.Call[T] {
-	return Connector[T]{}
+func NewConnector[T any](opts ...shared_kernel.Option) connector.Call[T] {
+	return Connector[T]{
+		options: shared_kernel.NewOptions(opts...),
+	}
 }
 
 func (c Connector[T]) Strings(parameter connector.Parameter, response *[]string) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Find(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) List(parameter connector.Parameter, response *[]T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Page(parameter connector.Parameter, response *connector.ListResponse[T]) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
+}
+
+func (c Connector[T]) Cursor(parameter connector.Parameter, response *connector.CursorResponse[T]) error {
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Ids(parameter connector.Parameter, response *[]int64) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Create(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Update(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
 func (c Connector[T]) Inative(parameter connector.Parameter, response *T) error {
-	return call(parameter, response)
+	return call(c.options, parameter, response)
 }
 
-func call(parameter connector.Parameter, response interface{}) error {
-	logrus.Debugf("Calling REST API\n")
-	logrus.Debugf("Resource: %s\n", parameter.Resource)
-	logrus.Debugf("Host: %s\n", parameter.Host)
-	logrus.Debugf("Body: %v\n", parameter.Body)
-	logrus.Debugf("Method: %s\n", strings.ToUpper(parameter.Method))
+func call(options shared_kernel.Options, parameter connector.Parameter, response interface{}) error {
+	headers, err := options.ApplyCredentials(context.Background(), parameter.Headers)
+	if err != nil {
+		return err
+	}
+	parameter.Headers = parameter.ResolveIdentity(context.Background()).Apply(headers)
+	parameter.Headers = options.ApplyIdempotencyKey(context.Background(), parameter.Method, parameter.Headers)
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
@@ -70,120 +76,46 @@ func call(parameter connector.Parameter, response interface{}) error {
 		return fmt.Errorf("resource invalid")
 	}
 
-	uri := parameter.GetHttpURL()
+	uri := options.URL(parameter.Host, parameter.Resource)
 	req.SetRequestURI(uri)
 	req.Header.SetMethod(method)
-	req.Header.Set("Accept", "application/json")
-
-	req.Header.Set("Authorization", parameter.Headers["Authorization"])
-	req.Header.Set("x-api-key", parameter.Headers["x-api-key"])
-
-	isFormData := false
-
-	if v, ok := parameter.Headers["Content-Type"]; ok {
-		if strings.HasPrefix(v, "multipart/form-data") {
-			req.Header.Set("Content-Type", "multipart/form-data")
-			isFormData = true
-		} else {
-			req.Header.Set("Content-Type", "application/json")
-		}
-	} else {
-		req.Header.Set("Content-Type", "application/json") // default
-	}
-
-	req.Header.Set("X-authenticated-user", parameter.UserID.String())
-	req.Header.Set("X-user-pool", parameter.UserPoolID)
-
-	logHeaders(&req.Header)
-
-	if method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE" {
-
-		if isFormData {
-			var b bytes.Buffer
-			writer := multipart.NewWriter(&b)
-
-			if form, ok := parameter.Body.(*multipart.Form); ok {
-				for key, vals := range form.Value {
-					for _, val := range vals {
-						if err := writer.WriteField(key, val); err != nil {
-							return fmt.Errorf("error writing form field %s: %w", key, err)
-						}
-					}
-				}
-
-				// Adiciona arquivos se houver
-				for key, files := range form.File {
-					for _, fh := range files {
-						fileWriter, err := writer.CreateFormFile(key, fh.Filename)
-						if err != nil {
-							return fmt.Errorf("error creating form file %s: %w", key, err)
-						}
-
-						// abre o arquivo
-						file, err := fh.Open()
-						if err != nil {
-							return fmt.Errorf("error opening file %s: %w", key, err)
-						}
-						defer file.Close()
-
-						if _, err := io.Copy(fileWriter, file); err != nil {
-							return fmt.Errorf("error copying file %s: %w", key, err)
-						}
-					}
-				}
-			}
-
-			writer.Close()
-			req.SetBody(b.Bytes())
-			req.Header.Set("Content-Type", writer.FormDataContentType())
-		} else {
-			requestBody, err := json.Marshal(parameter.Body)
-			if err != nil {
-				return fmt.Errorf("error marshaling request body: %w", err)
-			}
-			req.SetBody(requestBody)
-		}
+	req.Header.Set("Accept", options.Codec.ContentType())
 
+	for key, value := range parameter.Headers {
+		req.Header.Set(key, value)
 	}
 
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseResponse(resp)
-	err := fasthttp.Do(req, resp)
+	encoded, err := options.EncodeRequest(method, parameter.Body)
 	if err != nil {
 		return err
 	}
+	setBody(req, encoded)
 
-	logrus.Debugf("Response status code: %d\n", resp.StatusCode())
-	if resp.Body() != nil {
-		logrus.Debugf("Response body: %s\n", resp.Body)
+	resp, err := execute(context.Background(), options, req, encoded)
+	if err != nil {
+		return err
 	}
 
-	if resp.StatusCode() == 204 {
+	if resp.statusCode == 204 {
 		return nil
 	}
 
-	if resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
-		err := json.Unmarshal(resp.Body(), &response)
+	if resp.statusCode >= 200 && resp.statusCode < 300 {
+		err := options.Decode(resp.header.Get("Content-Type"), resp.body, response)
 		if err != nil {
 			return err
 		}
 		return nil
 	}
 
-	var errResponse connector.Result[string]
-	err = json.Unmarshal(resp.Body(), &errResponse)
-	if err != nil {
-		return err
-	}
-	return errors.New(errResponse.Content)
+	return options.DecodeError(resp.statusCode, resp.body)
 }
 
-func Call[T any](parameter *connector.Parameter, response *T) error {
-	logrus.Debugf("Calling REST API\n")
-	logrus.Debugf("Resource: %s\n", parameter.Resource)
-	logrus.Debugf("Host: %s\n", parameter.Host)
-	logrus.Debugf("Body: %v\n", parameter.Body)
-	logrus.Debugf("Method: %s\n", strings.ToUpper(parameter.Method))
+// Call sends parameter and decodes the response, unwrapping a
+// connector.Result envelope unless opts set another one. opts are resolved on
+// every call, against the clients shared by equal HTTP configurations.
+func Call[T any](parameter *connector.Parameter, response *T, opts ...shared_kernel.Option) error {
+	options := shared_kernel.NewOptions(append([]shared_kernel.Option{shared_kernel.WithEnvelope(envelope.Result)}, opts...)...)
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
@@ -192,60 +124,44 @@ func Call[T any](parameter *connector.Parameter, response *T) error {
 		return fmt.Errorf("resource invalid")
 	}
 
-	uri := parameter.GetHttpURL()
+	uri := options.URL(parameter.Host, parameter.Resource)
 	req.SetRequestURI(uri)
 	req.Header.SetMethod(method)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-authenticated-user", parameter.UserID.String())
-	req.Header.Set("X-user-pool", parameter.UserPoolID)
-
-	logHeaders(&req.Header)
-
-	if method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE" {
-		requestBody, err := json.Marshal(parameter.Body)
-		if err != nil {
-			return fmt.Errorf("error marshaling request body: %w", err)
-		}
-		req.SetBody(requestBody)
+	req.Header.Set("Accept", options.Codec.ContentType())
+	for key, value := range parameter.ResolveIdentity(context.Background()).Headers() {
+		req.Header.Set(key, value)
 	}
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseResponse(resp)
-	err := fasthttp.Do(req, resp)
+
+	headers, err := options.ApplyCredentials(context.Background(), parameter.Headers)
 	if err != nil {
 		return err
 	}
-
-	logrus.Debugf("Response status code: %d\n", resp.StatusCode())
-	if resp.Body() != nil {
-		logrus.Debugf("Response body: %s\n", resp.Body)
+	for _, key := range []string{"Authorization", "x-api-key"} {
+		if value, ok := headers[key]; ok {
+			req.Header.Set(key, value)
+		}
 	}
-
-	if resp.StatusCode() == 204 {
-		return nil
+	if key := idempotency.Of(options.ApplyIdempotencyKey(context.Background(), method, parameter.Headers)); key != "" {
+		req.Header.Set(idempotency.Header, key)
 	}
-	var result connector.Result[T]
 
-	if resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
-		err := json.Unmarshal(resp.Body(), &result)
-		if err != nil {
-			return err
-		}
-		*response = result.Content
-		return nil
+	encoded, err := options.EncodeRequest(method, parameter.Body)
+	if err != nil {
+		return err
 	}
-	var errResponse connector.Result[string]
-	err = json.Unmarshal(resp.Body(), &errResponse)
+	setBody(req, encoded)
+
+	resp, err := execute(context.Background(), options, req, encoded)
 	if err != nil {
 		return err
 	}
-	return errors.New(errResponse.Content)
-}
 
-func logHeaders(headers *fasthttp.RequestHeader) {
-	if headers != nil {
-		headers.VisitAll(func(key, value []byte) {
-			logrus.Debugf("Header: %s: %s\n", key, value)
-		})
+	if resp.statusCode == 204 {
+		return nil
+	}
+
+	if resp.statusCode >= 200 && resp.statusCode < 300 {
+		return options.Decode(resp.header.Get("Content-Type"), resp.body, response)
 	}
+	return options.DecodeError(resp.statusCode, resp.body)
 }
@@ -1,27 +1,26 @@
 package client_rest
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"github.com/sirupsen/logrus"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+	"github.com/tecmise/connector-lib/pkg/ports/output/identity"
 	"github.com/valyala/fasthttp"
-	"io"
-	"mime/multipart"
 	"strings"
 )
 
-func NewClient[T any, R any](serviceName string) RestProxyProtocolClient[T, R] {
+func NewClient[T any, R any](serviceName string, opts ...shared_kernel.Option) RestProxyProtocolClient[T, R] {
 	return &protocolClient[T, R]{
 		serviceName: serviceName,
+		options:     shared_kernel.NewOptions(opts...),
 	}
 }
 
 type (
 	protocolClient[Request any, Response any] struct {
 		serviceName string
+		options     shared_kernel.Options
 	}
 
 	RestProxyProtocolClient[Request any, Response any] interface {
@@ -30,6 +29,9 @@ type (
 		PUT(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error)
 		PATCH(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error)
 		DELETE(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error)
+		// Download streams the response of a GET, failing with ErrBodyTooLarge
+		// past maxSize bytes when maxSize is positive. The caller must close it.
+		Download(ctx context.Context, resource string, headers map[string]string, maxSize int64) (*Download, error)
 	}
 
 	requestObject struct {
@@ -41,13 +43,9 @@ type (
 	}
 )
 
-func (r *requestObject) getUrl() string {
-	return fmt.Sprintf("%s/%s", r.Host, r.Resource)
-}
-
 func (p protocolClient[Request, Response]) GET(ctx context.Context, resource string, headers map[string]string) (*Response, error) {
 	var response Response
-	return &response, sendRequest(ctx, requestObject{
+	return &response, sendRequest(ctx, p.options, requestObject{
 		Resource: resource,
 		Method:   "GET",
 		Body:     nil,
@@ -58,7 +56,7 @@ func (p protocolClient[Request, Response]) GET(ctx context.Context, resource str
 
 func (p protocolClient[Request, Response]) POST(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error) {
 	var response Response
-	return &response, sendRequest(ctx, requestObject{
+	return &response, sendRequest(ctx, p.options, requestObject{
 		Resource: resource,
 		Method:   "POST",
 		Body:     body,
@@ -69,7 +67,7 @@ func (p protocolClient[Request, Response]) POST(ctx context.Context, resource st
 
 func (p protocolClient[Request, Response]) PUT(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error) {
 	var response Response
-	return &response, sendRequest(ctx, requestObject{
+	return &response, sendRequest(ctx, p.options, requestObject{
 		Resource: resource,
 		Method:   "PUT",
 		Body:     body,
@@ -80,7 +78,7 @@ func (p protocolClient[Request, Response]) PUT(ctx context.Context, resource str
 
 func (p protocolClient[Request, Response]) PATCH(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error) {
 	var response Response
-	return &response, sendRequest(ctx, requestObject{
+	return &response, sendRequest(ctx, p.options, requestObject{
 		Resource: resource,
 		Method:   "PATCH",
 		Body:     body,
@@ -91,7 +89,7 @@ func (p protocolClient[Request, Response]) PATCH(ctx context.Context, resource s
 
 func (p protocolClient[Request, Response]) DELETE(ctx context.Context, resource string, body *Request, headers map[string]string) (*Response, error) {
 	var response Response
-	return &response, sendRequest(ctx, requestObject{
+	return &response, sendRequest(ctx, p.options, requestObject{
 		Resource: resource,
 		Method:   "DELETE",
 		Body:     body,
@@ -100,107 +98,76 @@ func (p protocolClient[Request, Response]) DELETE(ctx context.Context, resource
 	}, &response)
 }
 
-func sendRequest(ctx context.Context, param requestObject, response interface{}) error {
-	logrus.Debugf("[connector] Resource: %s\n", param.Resource)
-	logrus.Debugf("[connector] Host: %s\n", param.Host)
-	logrus.Debugf("[connector] Body: %v\n", param.Body)
-	logrus.Debugf("[connector] Method: %s\n", strings.ToUpper(param.Method))
+func sendRequest(ctx context.Context, options shared_kernel.Options, param requestObject, response interface{}) error {
+	headers, err := resolveHeaders(ctx, options, param.Headers)
+	if err != nil {
+		return err
+	}
+	param.Headers = options.ApplyIdempotencyKey(ctx, param.Method, headers)
+
+	if cursor, ok := connector.CursorFromContext(ctx); ok {
+		param.Resource, param.Headers = options.CursorStrategy.Apply(param.Resource, param.Headers, cursor)
+	}
+	if options.Cache != nil && strings.ToUpper(param.Method) == fasthttp.MethodGet {
+		return sendCachedRequest(ctx, options, param, response)
+	}
+	resp, err := doRequest(ctx, options, param)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(options, resp, response)
+}
 
+// resolveHeaders adds the credentials and the caller identity to headers.
+func resolveHeaders(ctx context.Context, options shared_kernel.Options, headers map[string]string) (map[string]string, error) {
+	headers, err := options.ApplyCredentials(ctx, headers)
+	if err != nil {
+		return nil, err
+	}
+	if caller, ok := identity.FromContext(ctx); ok {
+		headers = caller.Apply(headers)
+	}
+	return headers, nil
+}
+
+func doRequest(ctx context.Context, options shared_kernel.Options, param requestObject) (*rawResponse, error) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 	method := strings.ToUpper(param.Method)
 	if strings.HasPrefix(param.Resource, "/") {
-		return fmt.Errorf("resource invalid")
+		return nil, fmt.Errorf("resource invalid")
 	}
 
-	uri := param.getUrl()
+	uri := options.URL(param.Host, param.Resource)
 	req.SetRequestURI(uri)
 	req.Header.SetMethod(method)
-	req.Header.Set("Accept", "application/json")
-
-	isFormData := false
+	req.Header.Set("Accept", options.Codec.ContentType())
 
 	for key, value := range param.Headers {
-		logrus.Debugf("[connector] Header: %s: %s\n", key, value)
 		req.Header.Set(key, value)
-		if key == "Content-Type" {
-			if strings.HasPrefix(value, "multipart/form-data") {
-				isFormData = true
-
-			}
-		}
 	}
 
-	if method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE" {
-		if isFormData {
-			var b bytes.Buffer
-			writer := multipart.NewWriter(&b)
-
-			if form, ok := param.Body.(*multipart.Form); ok {
-				for key, vals := range form.Value {
-					for _, val := range vals {
-						if err := writer.WriteField(key, val); err != nil {
-							return fmt.Errorf("error writing form field %s: %w", key, err)
-						}
-					}
-				}
-
-				for key, files := range form.File {
-					for _, fh := range files {
-						fileWriter, err := writer.CreateFormFile(key, fh.Filename)
-						if err != nil {
-							return fmt.Errorf("error creating form file %s: %w", key, err)
-						}
-
-						file, err := fh.Open()
-						if err != nil {
-							return fmt.Errorf("error opening file %s: %w", key, err)
-						}
-						defer file.Close()
-
-						if _, err := io.Copy(fileWriter, file); err != nil {
-							return fmt.Errorf("error copying file %s: %w", key, err)
-						}
-					}
-				}
-			}
-
-			writer.Close()
-			req.SetBody(b.Bytes())
-			req.Header.Set("Content-Type", writer.FormDataContentType())
-		} else {
-			requestBody, err := json.Marshal(param.Body)
-			if err != nil {
-				return fmt.Errorf("error marshaling request body: %w", err)
-			}
-			req.SetBody(requestBody)
-		}
-
-	}
-
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseResponse(resp)
-	err := fasthttp.Do(req, resp)
+	encoded, err := options.EncodeRequest(method, param.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	setBody(req, encoded)
 
-	logrus.Debugf("Response status code: %d\n", resp.StatusCode())
-	if resp.Body() != nil {
-		logrus.Debugf("Response body: %s\n", resp.Body)
-	}
+	return execute(ctx, options, req, encoded)
+}
 
-	if resp.StatusCode() == 204 {
+func decodeResponse(options shared_kernel.Options, resp *rawResponse, response interface{}) error {
+	if resp.statusCode == 204 {
 		return nil
 	}
 
-	if resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
-		err := json.Unmarshal(resp.Body(), &response)
+	if resp.statusCode >= 200 && resp.statusCode < 300 {
+		err := options.Decode(resp.header.Get("Content-Type"), resp.body, response)
 		if err != nil {
 			return err
 		}
 		return nil
 	}
 
-	return fmt.Errorf("{ \"status_code\": %d, \"content\": \"%v\" }", resp.StatusCode(), base64.StdEncoding.EncodeToString(resp.Body()))
+	return options.DecodeError(resp.statusCode, resp.body)
 }
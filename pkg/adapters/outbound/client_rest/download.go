@@ -0,0 +1,194 @@
+package client_rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/valyala/fasthttp"
+)
+
+// maxErrorBody bounds what is read of a failed download to build its error.
+const maxErrorBody = 64 << 10
+
+var ErrBodyTooLarge = errors.New("response body exceeds the maximum size")
+
+type (
+	// Download is a response whose body is read from the connection as the
+	// caller consumes it. ContentLength is -1 when the server did not send it.
+	Download struct {
+		StatusCode    int
+		Header        http.Header
+		ContentLength int64
+		Body          io.ReadCloser
+	}
+
+	downloadBody struct {
+		ctx     context.Context
+		resp    *fasthttp.Response
+		body    io.Reader
+		read    int64
+		maxSize int64
+		release func()
+	}
+)
+
+func (p protocolClient[Request, Response]) Download(ctx context.Context, resource string, headers map[string]string, maxSize int64) (*Download, error) {
+	if strings.HasPrefix(resource, "/") {
+		return nil, fmt.Errorf("resource invalid")
+	}
+	headers, err := resolveHeaders(ctx, p.options, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(p.options.URL(p.serviceName, resource))
+	req.Header.SetMethod(fasthttp.MethodGet)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return download(ctx, p.options, req, maxSize)
+}
+
+func (d *Download) Close() error {
+	return d.Body.Close()
+}
+
+func download(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request, maxSize int64) (*Download, error) {
+	if options.Signer != nil {
+		if err := options.Signer.Sign(ctx, req); err != nil {
+			options.Logger.ErrorContext(ctx, "failed to sign rest request", "url", req.URI().String(), "error", err)
+			return nil, err
+		}
+	}
+	logRequest(ctx, options, req, nil)
+
+	limiter := options.Limiters.For(string(req.URI().Host()))
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		options.Logger.WarnContext(ctx, "rest request not sent", "url", req.URI().String(), "error", err)
+		return nil, err
+	}
+
+	resp, err := doStream(ctx, options, req)
+	if err != nil {
+		release()
+		options.Logger.ErrorContext(ctx, "rest request failed", "method", string(req.Header.Method()), "url", req.URI().String(), "error", err)
+		return nil, err
+	}
+
+	limiter.ObserveStatus(resp.StatusCode(), string(resp.Header.Peek(fasthttp.HeaderRetryAfter)))
+
+	body := &downloadBody{ctx: ctx, resp: resp, body: resp.BodyStream(), maxSize: maxSize, release: release}
+	if body.body == nil {
+		body.body = bytes.NewReader(resp.Body())
+	}
+	contentLength := int64(resp.Header.ContentLength())
+	if contentLength < 0 {
+		contentLength = -1
+	}
+	options.Logger.DebugContext(ctx, "received rest download", "status_code", resp.StatusCode(), "content_length", contentLength)
+
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		defer body.Close()
+		data, _ := io.ReadAll(io.LimitReader(body.body, maxErrorBody))
+		return nil, decodeResponse(options, &rawResponse{statusCode: resp.StatusCode(), body: data}, nil)
+	}
+	if maxSize > 0 && contentLength > maxSize {
+		body.Close()
+		return nil, fmt.Errorf("%w: %d bytes announced, %d allowed", ErrBodyTooLarge, contentLength, maxSize)
+	}
+
+	download := &Download{
+		StatusCode:    resp.StatusCode(),
+		Header:        http.Header{},
+		ContentLength: contentLength,
+		Body:          body,
+	}
+	resp.Header.VisitAll(func(key, value []byte) {
+		download.Header.Add(string(key), string(value))
+	})
+	return download, nil
+}
+
+// doStream sends req through the streaming client. HTTP.Timeout only bounds
+// the wait for the response headers: the body is then read at the pace of the
+// caller, bounded by ctx and by HTTP.ReadTimeout between two reads.
+func doStream(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request) (*fasthttp.Response, error) {
+	sent := fasthttp.AcquireRequest()
+	req.CopyTo(sent)
+	resp := fasthttp.AcquireResponse()
+	resp.StreamBody = true
+
+	done := make(chan error, 1)
+	go func() {
+		err := options.StreamClient.Do(sent, resp)
+		fasthttp.ReleaseRequest(sent)
+		done <- err
+	}()
+
+	var timeout <-chan time.Time
+	if options.HTTP.Timeout > 0 {
+		timer := time.NewTimer(options.HTTP.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			fasthttp.ReleaseResponse(resp)
+			return nil, err
+		}
+		return resp, nil
+	case <-ctx.Done():
+		go abandon(done, resp)
+		return nil, ctx.Err()
+	case <-timeout:
+		go abandon(done, resp)
+		return nil, fasthttp.ErrTimeout
+	}
+}
+
+// abandon releases the response of a request the caller stopped waiting for,
+// once the client is done with it.
+func abandon(done <-chan error, resp *fasthttp.Response) {
+	if err := <-done; err == nil {
+		_ = resp.CloseBodyStream()
+	}
+	fasthttp.ReleaseResponse(resp)
+}
+
+func (b *downloadBody) Read(p []byte) (int, error) {
+	if b.resp == nil {
+		return 0, io.ErrClosedPipe
+	}
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	if b.maxSize > 0 && b.read > b.maxSize {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, b.maxSize)
+	}
+	return n, err
+}
+
+func (b *downloadBody) Close() error {
+	if b.resp == nil {
+		return nil
+	}
+	err := b.resp.CloseBodyStream()
+	fasthttp.ReleaseResponse(b.resp)
+	b.resp = nil
+	b.release()
+	return err
+}
@@ -0,0 +1,52 @@
+package client_rest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/valyala/fasthttp"
+)
+
+// logRequest logs req as sent, with the body as encoded before compression.
+func logRequest(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request, encoded *shared_kernel.EncodedBody) {
+	if !options.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	var body string
+	switch {
+	case encoded == nil:
+	case encoded.Stream != nil:
+		body = fmt.Sprintf("[%s, streamed]", encoded.ContentType)
+	case encoded.Binary:
+		body = fmt.Sprintf("[%s, %d bytes]", encoded.ContentType, len(encoded.Data))
+	default:
+		body = options.Redactor.Body(encoded.Data)
+	}
+	options.Logger.DebugContext(ctx, "sending rest request",
+		"method", string(req.Header.Method()),
+		"url", req.URI().String(),
+		"headers", requestHeaders(options, &req.Header),
+		"body", body,
+	)
+}
+
+// logResponse logs resp with its body once decompressed.
+func logResponse(ctx context.Context, options shared_kernel.Options, resp *fasthttp.Response, body []byte) {
+	if !options.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	options.Logger.DebugContext(ctx, "received rest response",
+		"status_code", resp.StatusCode(),
+		"body", options.Redactor.Body(body),
+	)
+}
+
+func requestHeaders(options shared_kernel.Options, headers *fasthttp.RequestHeader) map[string]string {
+	result := map[string]string{}
+	headers.VisitAll(func(key, value []byte) {
+		result[string(key)] = options.Redactor.Header(string(key), string(value))
+	})
+	return result
+}
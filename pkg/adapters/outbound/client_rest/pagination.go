@@ -0,0 +1,23 @@
+package client_rest
+
+import (
+	"context"
+
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+)
+
+// Pages adapts a v2 client to connector.PageFetcher, resource returns the
+// resource (with its page query parameter) of a given page.
+func Pages[T any, Request any](client RestProxyProtocolClient[Request, connector.ListResponse[T]], resource func(page int) string, headers map[string]string) connector.PageFetcher[T] {
+	return func(ctx context.Context, page int) (*connector.ListResponse[T], error) {
+		return client.GET(ctx, resource(page), headers)
+	}
+}
+
+// Cursors adapts a v2 client to connector.CursorFetcher. The cursor is sent
+// according to the client's cursor strategy.
+func Cursors[T any, Request any](client RestProxyProtocolClient[Request, connector.CursorResponse[T]], resource string, headers map[string]string) connector.CursorFetcher[T] {
+	return func(ctx context.Context, cursor string) (*connector.CursorResponse[T], error) {
+		return client.GET(connector.WithCursor(ctx, cursor), resource, headers)
+	}
+}
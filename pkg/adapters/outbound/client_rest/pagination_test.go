@@ -0,0 +1,51 @@
+package client_rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/cache"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+)
+
+func TestCachedCursorPagination(t *testing.T) {
+	strategies := map[string]connector.CursorStrategy{
+		"query":  {In: connector.CursorInQuery, Name: "cursor"},
+		"header": {In: connector.CursorInHeader, Name: "X-Cursor"},
+	}
+	for name, strategy := range strategies {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cursor := r.URL.Query().Get("cursor")
+				if strategy.In == connector.CursorInHeader {
+					cursor = r.Header.Get("X-Cursor")
+				}
+				page := map[string]string{"": `{"data":[1,2],"nextCursor":"b"}`, "b": `{"data":[3,4],"nextCursor":"c"}`, "c": `{"data":[5]}`}[cursor]
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, page)
+			}))
+			defer server.Close()
+
+			client := NewClient[struct{}, connector.CursorResponse[int]]("",
+				shared_kernel.WithBaseURL(server.URL),
+				shared_kernel.WithCache(cache.New()),
+				shared_kernel.WithCursorStrategy(strategy),
+			)
+			var items []int
+			for item, err := range connector.PaginateCursor(context.Background(), Cursors(client, "items?limit=2", nil)) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				items = append(items, item)
+			}
+			if want := []int{1, 2, 3, 4, 5}; !slices.Equal(items, want) {
+				t.Fatalf("items = %v, want %v", items, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package client_rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/hedge"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/ratelimit"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/retry"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	rawResponse struct {
+		statusCode int
+		header     http.Header
+		body       []byte
+	}
+)
+
+// execute sends req and returns a copy of the response detached from the
+// fasthttp pools. Concurrent identical GETs share one round trip when the
+// client has a coalescing group.
+func execute(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request, encoded *shared_kernel.EncodedBody) (*rawResponse, error) {
+	if !options.DisableResponseCompression && len(req.Header.Peek(fasthttp.HeaderAcceptEncoding)) == 0 {
+		req.Header.Set(fasthttp.HeaderAcceptEncoding, shared_kernel.AcceptEncoding)
+	}
+	if err := compressBody(options, req); err != nil {
+		return nil, err
+	}
+
+	coalesce := options.Coalescer != nil && string(req.Header.Method()) == fasthttp.MethodGet
+	var key string
+	if coalesce {
+		// computed before signing, the signature changes every second
+		key = requestKey(req)
+	}
+
+	if options.Signer != nil {
+		if err := options.Signer.Sign(ctx, req); err != nil {
+			options.Logger.ErrorContext(ctx, "failed to sign rest request", "url", req.URI().String(), "error", err)
+			return nil, err
+		}
+	}
+
+	logRequest(ctx, options, req, encoded)
+
+	if !coalesce {
+		return send(ctx, options, req)
+	}
+
+	shared := fasthttp.AcquireRequest()
+	req.CopyTo(shared)
+	value, coalesced, err := options.Coalescer.Do(ctx, key, func() (interface{}, error) {
+		defer fasthttp.ReleaseRequest(shared)
+		return send(context.WithoutCancel(ctx), options, shared)
+	})
+	if coalesced {
+		// our copy was not used, the call in flight had its own
+		fasthttp.ReleaseRequest(shared)
+		options.Logger.DebugContext(ctx, "rest request coalesced", "url", req.URI().String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value.(*rawResponse), nil
+}
+
+// send is hedgedRoundTrip retried by the policy of the client. Mutating
+// requests are only retried when they carry an idempotency key, and streamed
+// bodies never since they can not be replayed.
+func send(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request) (*rawResponse, error) {
+	method := string(req.Header.Method())
+	if options.Retry == nil || req.IsBodyStream() || (idempotency.Mutating(method) && len(req.Header.Peek(idempotency.Header)) == 0) {
+		return hedgedRoundTrip(ctx, options, req)
+	}
+
+	attempt := 0
+	return retry.Do(ctx, options.Retry, func(ctx context.Context) (*rawResponse, error) {
+		if attempt++; attempt > 1 {
+			options.Logger.DebugContext(ctx, "retrying rest request", "method", method, "url", req.URI().String(), "attempt", attempt)
+		}
+		return hedgedRoundTrip(ctx, options, req)
+	}, retryable)
+}
+
+// retryable retries transport errors, except the ones of the caller, and the
+// statuses of an overloaded or unreachable target.
+func retryable(resp *rawResponse, err error) (bool, time.Duration) {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ratelimit.ErrRateLimited), 0
+	}
+	switch resp.statusCode {
+	case fasthttp.StatusTooManyRequests, fasthttp.StatusBadGateway, fasthttp.StatusServiceUnavailable, fasthttp.StatusGatewayTimeout:
+		return true, ratelimit.ParseRetryAfter(resp.header.Get(fasthttp.HeaderRetryAfter))
+	}
+	return false, 0
+}
+
+// hedgedRoundTrip is roundTrip hedged by the policy of the client for GETs.
+// Each attempt sends its own copy of req, left to the garbage collector since
+// fasthttp can not abort a request: the slower attempt completes in the
+// background and is discarded.
+func hedgedRoundTrip(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request) (*rawResponse, error) {
+	if options.Hedge == nil || string(req.Header.Method()) != fasthttp.MethodGet {
+		return roundTrip(ctx, options, req)
+	}
+
+	copies := [2]*fasthttp.Request{{}, {}}
+	req.CopyTo(copies[0])
+	req.CopyTo(copies[1])
+	var attempts atomic.Int32
+	return hedge.Do(ctx, options.Hedge, func(ctx context.Context) (*rawResponse, error) {
+		attempt := attempts.Add(1)
+		if attempt > 1 {
+			options.Logger.DebugContext(ctx, "hedging rest request", "url", copies[1].URI().String())
+		}
+		return roundTrip(ctx, options, copies[attempt-1])
+	})
+}
+
+func roundTrip(ctx context.Context, options shared_kernel.Options, req *fasthttp.Request) (*rawResponse, error) {
+	limiter := options.Limiters.For(string(req.URI().Host()))
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		options.Logger.WarnContext(ctx, "rest request not sent", "url", req.URI().String(), "error", err)
+		return nil, err
+	}
+	defer release()
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := do(ctx, options, options.HTTPClient, req, resp); err != nil {
+		options.Logger.ErrorContext(ctx, "rest request failed", "method", string(req.Header.Method()), "url", req.URI().String(), "error", err)
+		return nil, err
+	}
+	limiter.ObserveStatus(resp.StatusCode(), string(resp.Header.Peek(fasthttp.HeaderRetryAfter)))
+
+	// BodyUncompressed returns a copy when the body was compressed
+	body, err := resp.BodyUncompressed()
+	if err != nil {
+		options.Logger.ErrorContext(ctx, "failed to decompress rest response", "url", req.URI().String(), "content_encoding", string(resp.Header.ContentEncoding()), "error", err)
+		return nil, err
+	}
+	if len(resp.Header.ContentEncoding()) == 0 {
+		body = append([]byte(nil), body...)
+	}
+	resp.Header.Del(fasthttp.HeaderContentEncoding)
+
+	logResponse(ctx, options, resp, body)
+
+	result := &rawResponse{
+		statusCode: resp.StatusCode(),
+		header:     http.Header{},
+		body:       body,
+	}
+	resp.Header.VisitAll(func(key, value []byte) {
+		result.header.Add(string(key), string(value))
+	})
+	return result, nil
+}
+
+// do sends req through client, bounded by the client timeout and the
+// deadline of ctx, whichever comes first.
+func do(ctx context.Context, options shared_kernel.Options, client *fasthttp.Client, req *fasthttp.Request, resp *fasthttp.Response) error {
+	deadline, ok := ctx.Deadline()
+	if options.HTTP.Timeout > 0 {
+		if timeout := time.Now().Add(options.HTTP.Timeout); !ok || timeout.Before(deadline) {
+			deadline, ok = timeout, true
+		}
+	}
+	if !ok {
+		return client.Do(req, resp)
+	}
+	return client.DoDeadline(req, resp, deadline)
+}
+
+// requestKey identifies a request by method, URI and every header, so callers
+// with different credentials or identities never share a response.
+func requestKey(req *fasthttp.Request) string {
+	var headers []string
+	req.Header.VisitAll(func(key, value []byte) {
+		headers = append(headers, string(key)+":"+string(value))
+	})
+	sort.Strings(headers)
+
+	hash := sha256.New()
+	hash.Write(req.Header.Method())
+	hash.Write([]byte(" " + req.URI().String()))
+	for _, h := range headers {
+		hash.Write([]byte("\n" + h))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,195 @@
+package client_rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/ratelimit"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/retry"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
+)
+
+func TestRetries(t *testing.T) {
+	policy := retry.Config{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+	tests := map[string]struct {
+		method     string
+		opts       []shared_kernel.Option
+		statuses   []int
+		retryAfter string
+		wantHits   int
+		wantErr    bool
+		minElapsed time.Duration
+	}{
+		"get retried until it succeeds": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			wantHits: 3,
+		},
+		"get given up after the last attempt": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			wantHits: 3,
+			wantErr:  true,
+		},
+		"client errors not retried": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusBadRequest, http.StatusOK},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		"post with a key retried": {
+			method:   http.MethodPost,
+			opts:     []shared_kernel.Option{shared_kernel.WithIdempotencyKeys()},
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantHits: 2,
+		},
+		"post without a key never retried": {
+			method:   http.MethodPost,
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		"put without a key never retried": {
+			method:   http.MethodPut,
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		"retry after honoured": {
+			method:     http.MethodGet,
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "0.2",
+			wantHits:   2,
+			minElapsed: 200 * time.Millisecond,
+		},
+		"retry after past the max delay not waited for": {
+			method:     http.MethodGet,
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "10",
+			wantHits:   1,
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				keys []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				keys = append(keys, r.Header.Get(idempotency.Header))
+				status := tt.statuses[len(keys)-1]
+				mu.Unlock()
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			opts := append([]shared_kernel.Option{shared_kernel.WithBaseURL(server.URL), shared_kernel.WithRetry(policy)}, tt.opts...)
+			client := NewClient[map[string]string, map[string]string]("", opts...)
+
+			start := time.Now()
+			var err error
+			switch tt.method {
+			case http.MethodGet:
+				_, err = client.GET(context.Background(), "items", nil)
+			case http.MethodPost:
+				_, err = client.POST(context.Background(), "items", &map[string]string{}, nil)
+			case http.MethodPut:
+				_, err = client.PUT(context.Background(), "items/1", &map[string]string{}, nil)
+			}
+			elapsed := time.Since(start)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(keys) != tt.wantHits {
+				t.Fatalf("server hit %d times, want %d", len(keys), tt.wantHits)
+			}
+			if elapsed < tt.minElapsed {
+				t.Fatalf("retried after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+			for _, key := range keys[1:] {
+				if key != keys[0] {
+					t.Fatalf("idempotency keys %q, want the same on every attempt", keys)
+				}
+			}
+			if tt.method != http.MethodGet && tt.wantHits > 1 && keys[0] == "" {
+				t.Fatal("retried a mutating request without an idempotency key")
+			}
+		})
+	}
+}
+
+func TestRetryKeepsTheKeyOfTheContext(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(idempotency.Header))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient[map[string]string, map[string]string]("", shared_kernel.WithBaseURL(server.URL),
+		shared_kernel.WithRetry(retry.Config{BaseDelay: time.Millisecond}))
+	ctx := idempotency.NewContext(context.Background(), "order-42")
+	if _, err := client.PATCH(ctx, "orders/42", &map[string]string{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "order-42,order-42" {
+		t.Fatalf("idempotency keys %q, want order-42 on both attempts", keys)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	response := func(status int, retryAfter string) *rawResponse {
+		header := http.Header{}
+		if retryAfter != "" {
+			header.Set("Retry-After", retryAfter)
+		}
+		return &rawResponse{statusCode: status, header: header}
+	}
+	tests := map[string]struct {
+		resp      *rawResponse
+		err       error
+		wantRetry bool
+		wantAfter time.Duration
+	}{
+		"transport error":   {err: errors.New("connection reset"), wantRetry: true},
+		"rate limited":      {err: fmt.Errorf("%w: api.example.com", ratelimit.ErrRateLimited)},
+		"cancelled":         {err: context.Canceled},
+		"deadline exceeded": {err: fmt.Errorf("request: %w", context.DeadlineExceeded)},
+		"ok":                {resp: response(http.StatusOK, "")},
+		"not found":         {resp: response(http.StatusNotFound, "")},
+		"internal error":    {resp: response(http.StatusInternalServerError, "")},
+		"bad gateway":       {resp: response(http.StatusBadGateway, ""), wantRetry: true},
+		"unavailable":       {resp: response(http.StatusServiceUnavailable, "2"), wantRetry: true, wantAfter: 2 * time.Second},
+		"gateway timeout":   {resp: response(http.StatusGatewayTimeout, ""), wantRetry: true},
+		"too many requests": {resp: response(http.StatusTooManyRequests, "1.5"), wantRetry: true, wantAfter: 1500 * time.Millisecond},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			retried, after := retryable(tt.resp, tt.err)
+			if retried != tt.wantRetry || after != tt.wantAfter {
+				t.Fatalf("retryable = %v, %v, want %v, %v", retried, after, tt.wantRetry, tt.wantAfter)
+			}
+		})
+	}
+}
@@ -2,14 +2,13 @@ package client_sns
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
-	"github.com/sirupsen/logrus"
 	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
 	"github.com/tecmise/connector-lib/pkg/ports/output/assync"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
 	"github.com/tecmise/connector-lib/pkg/ports/output/request"
 	"strings"
 )
@@ -22,13 +21,15 @@ type (
 	assyncPublisherSns struct {
 		client     *sns.Client
 		identifier string
+		options    shared_kernel.Options
 	}
 )
 
-func NewPublisher(client *sns.Client, identifier string) AssyncPublisherSns {
+func NewPublisher(client *sns.Client, identifier string, opts ...shared_kernel.Option) AssyncPublisherSns {
 	return &assyncPublisherSns{
 		client:     client,
 		identifier: identifier,
+		options:    shared_kernel.NewOptions(opts...),
 	}
 }
 
@@ -37,12 +38,6 @@ func (a assyncPublisherSns) Publish(ctx context.Context, req request.Validatable
 		return nil, err
 	}
 
-	content, err := json.Marshal(req)
-	if err != nil {
-		logrus.Error("error marshaling request:", err)
-		return nil, err
-	}
-
 	isFifo := strings.HasSuffix(topicArn, ".fifo")
 
 	if fifoData != nil && !isFifo {
@@ -55,7 +50,6 @@ func (a assyncPublisherSns) Publish(ctx context.Context, req request.Validatable
 	input := &sns.PublishInput{
 		TopicArn: aws.String(topicArn),
 		Subject:  aws.String(subject),
-		Message:  aws.String(string(content)),
 		MessageAttributes: map[string]types.MessageAttributeValue{
 			"kind": {
 				DataType:    aws.String("String"),
@@ -77,16 +71,53 @@ func (a assyncPublisherSns) Publish(ctx context.Context, req request.Validatable
 		}
 	}
 
+	// compression is dropped when the other attributes leave no room for
+	// the one describing the body
+	content, err := a.options.EncodeMessage(req, len(input.MessageAttributes))
+	if err != nil {
+		a.options.Logger.ErrorContext(ctx, "failed to marshal message", "error", err)
+		return nil, err
+	}
+	input.Message = aws.String(content.Body)
+	for k, v := range content.Attributes {
+		input.MessageAttributes[k] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
+	if err := shared_kernel.CheckMessageAttributes(len(input.MessageAttributes)); err != nil {
+		a.options.Logger.ErrorContext(ctx, "invalid message attributes", "topic", topicArn, "error", err)
+		return nil, err
+	}
+
 	if isFifo {
 		input.MessageGroupId = aws.String(fifoData.MessageGroupId)
-		if fifoData.MessageDeduplicationId != "" {
-			input.MessageDeduplicationId = aws.String(fifoData.MessageDeduplicationId)
+		// a missing deduplication id falls back to the idempotency key the
+		// caller put in ctx, so that the sends of one operation share it,
+		// and otherwise to the content-based deduplication of the topic
+		deduplicationID := fifoData.MessageDeduplicationId
+		if deduplicationID == "" {
+			deduplicationID, _ = idempotency.FromContext(ctx)
+		}
+		if deduplicationID != "" {
+			input.MessageDeduplicationId = aws.String(deduplicationID)
 		}
 	}
 
+	a.options.Logger.DebugContext(ctx, "publishing message", "topic", topicArn, "body", content.Loggable(a.options))
+
+	limiter := a.options.Limiters.For(topicArn)
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		a.options.Logger.WarnContext(ctx, "message not published", "topic", topicArn, "error", err)
+		return nil, err
+	}
 	message, err := a.client.Publish(ctx, input)
+	release()
+	limiter.Observe(err)
 	if err != nil {
-		logrus.Error("error sending message:", err)
+		a.options.Logger.ErrorContext(ctx, "failed to publish message", "topic", topicArn, "error", err)
 		return nil, err
 	}
 
@@ -2,14 +2,13 @@ package client_sqs
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
-	"github.com/sirupsen/logrus"
 	"github.com/tecmise/connector-lib/pkg/adapters/outbound/shared_kernel"
 	"github.com/tecmise/connector-lib/pkg/ports/output/assync"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
 	"github.com/tecmise/connector-lib/pkg/ports/output/request"
 	"strings"
 )
@@ -22,13 +21,15 @@ type (
 	assyncPublisher struct {
 		client     *sqs.Client
 		identifier string
+		options    shared_kernel.Options
 	}
 )
 
-func NewAssyncPublisher(client *sqs.Client, identifier string) AssyncPublisher {
+func NewAssyncPublisher(client *sqs.Client, identifier string, opts ...shared_kernel.Option) AssyncPublisher {
 	return &assyncPublisher{
 		client:     client,
 		identifier: identifier,
+		options:    shared_kernel.NewOptions(opts...),
 	}
 }
 func (a assyncPublisher) Publish(ctx context.Context, req request.Validatable, queueUrl string, fifoData *shared_kernel.FifoProperties, attrs map[string]string) (*assync.QueueTriggerResponse, error) {
@@ -36,12 +37,6 @@ func (a assyncPublisher) Publish(ctx context.Context, req request.Validatable, q
 		return nil, err
 	}
 	queueURL := queueUrl
-	content, err := json.Marshal(req)
-	if err != nil {
-		logrus.Fatal("Error", err)
-		return nil, err
-	}
-
 	isFifo := strings.HasSuffix(queueUrl, ".fifo")
 
 	if fifoData != nil && !isFifo {
@@ -52,8 +47,7 @@ func (a assyncPublisher) Publish(ctx context.Context, req request.Validatable, q
 	}
 
 	input := sqs.SendMessageInput{
-		QueueUrl:    aws.String(queueURL),
-		MessageBody: aws.String(string(content)),
+		QueueUrl: aws.String(queueURL),
 		MessageAttributes: map[string]types.MessageAttributeValue{
 			"kind": {
 				DataType:    aws.String("String"),
@@ -75,17 +69,53 @@ func (a assyncPublisher) Publish(ctx context.Context, req request.Validatable, q
 		}
 	}
 
+	// compression is dropped when the other attributes leave no room for
+	// the one describing the body
+	content, err := a.options.EncodeMessage(req, len(input.MessageAttributes))
+	if err != nil {
+		a.options.Logger.ErrorContext(ctx, "failed to marshal message", "error", err)
+		return nil, err
+	}
+	input.MessageBody = aws.String(content.Body)
+	for k, v := range content.Attributes {
+		input.MessageAttributes[k] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
+	if err := shared_kernel.CheckMessageAttributes(len(input.MessageAttributes)); err != nil {
+		a.options.Logger.ErrorContext(ctx, "invalid message attributes", "queue", queueURL, "error", err)
+		return nil, err
+	}
+
 	if isFifo {
 		input.MessageGroupId = aws.String(fifoData.MessageGroupId)
-		if fifoData.MessageDeduplicationId != "" {
-			input.MessageDeduplicationId = aws.String(fifoData.MessageDeduplicationId)
+		// a missing deduplication id falls back to the idempotency key the
+		// caller put in ctx, so that the sends of one operation share it,
+		// and otherwise to the content-based deduplication of the queue
+		deduplicationID := fifoData.MessageDeduplicationId
+		if deduplicationID == "" {
+			deduplicationID, _ = idempotency.FromContext(ctx)
+		}
+		if deduplicationID != "" {
+			input.MessageDeduplicationId = aws.String(deduplicationID)
 		}
 	}
 
-	message, err := a.client.SendMessage(ctx, &input)
+	a.options.Logger.DebugContext(ctx, "publishing message", "queue", queueURL, "body", content.Loggable(a.options))
 
+	limiter := a.options.Limiters.For(queueURL)
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		a.options.Logger.WarnContext(ctx, "message not sent", "queue", queueURL, "error", err)
+		return nil, err
+	}
+	message, err := a.client.SendMessage(ctx, &input)
+	release()
+	limiter.Observe(err)
 	if err != nil {
-		logrus.Fatal("Error", err)
+		a.options.Logger.ErrorContext(ctx, "failed to send message", "queue", queueURL, "error", err)
 		return nil, err
 	}
 
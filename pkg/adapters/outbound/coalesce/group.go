@@ -0,0 +1,81 @@
+package coalesce
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Group deduplicates concurrent calls sharing the same key: the first
+	// caller executes the call and the others wait for its result. A Group can
+	// be shared by several clients to coalesce across them.
+	Group struct {
+		mu        sync.Mutex
+		calls     map[string]*call
+		executed  atomic.Uint64
+		coalesced atomic.Uint64
+	}
+
+	Stats struct {
+		// Executed is the number of calls that actually reached the backend.
+		Executed uint64
+		// Coalesced is the number of calls saved by joining an in-flight one.
+		Coalesced uint64
+	}
+
+	call struct {
+		done  chan struct{}
+		value interface{}
+		err   error
+	}
+)
+
+func NewGroup() *Group {
+	return &Group{calls: map[string]*call{}}
+}
+
+// Do runs fn once for all concurrent callers of key. The value is shared, so
+// callers must treat it as read-only. fn runs detached from the caller, which
+// may stop waiting when ctx is done without cancelling the other waiters.
+func (g *Group) Do(ctx context.Context, key string, fn func() (interface{}, error)) (value interface{}, shared bool, err error) {
+	g.mu.Lock()
+	c, inFlight := g.calls[key]
+	if inFlight {
+		g.coalesced.Add(1)
+	} else {
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+		g.executed.Add(1)
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, inFlight, c.err
+	case <-ctx.Done():
+		return nil, inFlight, ctx.Err()
+	}
+}
+
+func (g *Group) Stats() Stats {
+	return Stats{
+		Executed:  g.executed.Load(),
+		Coalesced: g.coalesced.Load(),
+	}
+}
+
+func (g *Group) run(key string, c *call, fn func() (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("coalesced call panicked: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+}
@@ -0,0 +1,204 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blocking returns a call that counts its executions and waits for release.
+func blocking(executions *atomic.Int32, release <-chan struct{}, value interface{}, err error) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		executions.Add(1)
+		<-release
+		return value, err
+	}
+}
+
+// waitCoalesced waits until n callers joined the call in flight.
+func waitCoalesced(t *testing.T, g *Group, n uint64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for g.Stats().Coalesced < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers coalesced, want %d", g.Stats().Coalesced, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentCallsExecuteOnce(t *testing.T) {
+	const callers = 20
+	g := NewGroup()
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := blocking(&executions, release, "value", nil)
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, callers)
+	shared := make([]bool, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, coalesced, err := g.Do(context.Background(), "key", fn)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i], shared[i] = value, coalesced
+		}()
+	}
+	waitCoalesced(t, g, callers-1)
+	close(release)
+	wg.Wait()
+
+	if n := executions.Load(); n != 1 {
+		t.Fatalf("executed %d times, want 1", n)
+	}
+	leaders := 0
+	for i := range callers {
+		if results[i] != "value" {
+			t.Errorf("caller %d received %v", i, results[i])
+		}
+		if !shared[i] {
+			leaders++
+		}
+	}
+	if leaders != 1 {
+		t.Errorf("%d callers executed the call, want 1", leaders)
+	}
+	if stats := g.Stats(); stats.Executed != 1 || stats.Coalesced != callers-1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// the key is free again once the call completed
+	if _, coalesced, _ := g.Do(context.Background(), "key", func() (interface{}, error) { return nil, nil }); coalesced {
+		t.Error("a completed call was joined")
+	}
+}
+
+func TestFollowerCancellationDoesNotCancelTheCall(t *testing.T) {
+	g := NewGroup()
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := blocking(&executions, release, "value", nil)
+
+	leader := make(chan error, 1)
+	go func() {
+		value, _, err := g.Do(context.Background(), "key", fn)
+		if err == nil && value != "value" {
+			err = errors.New("leader received the wrong value")
+		}
+		leader <- err
+	}()
+	for executions.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	follower := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(ctx, "key", fn)
+		follower <- err
+	}()
+	waitCoalesced(t, g, 1)
+	cancel()
+	if err := <-follower; !errors.Is(err, context.Canceled) {
+		t.Fatalf("follower error = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	if err := <-leader; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaderCancellationDoesNotCancelTheCall(t *testing.T) {
+	g := NewGroup()
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := blocking(&executions, release, "value", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(ctx, "key", fn)
+		leader <- err
+	}()
+	for executions.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	follower := make(chan interface{}, 1)
+	go func() {
+		value, _, _ := g.Do(context.Background(), "key", fn)
+		follower <- value
+	}()
+	waitCoalesced(t, g, 1)
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader error = %v, want context.Canceled", err)
+	}
+	close(release)
+	if value := <-follower; value != "value" {
+		t.Fatalf("follower received %v", value)
+	}
+}
+
+func TestFailuresReachEveryCaller(t *testing.T) {
+	failure := errors.New("backend down")
+	tests := map[string]struct {
+		fn   func(release <-chan struct{}) func() (interface{}, error)
+		want func(error) bool
+	}{
+		"error": {
+			fn: func(release <-chan struct{}) func() (interface{}, error) {
+				var executions atomic.Int32
+				return blocking(&executions, release, nil, failure)
+			},
+			want: func(err error) bool { return errors.Is(err, failure) },
+		},
+		"panic": {
+			fn: func(release <-chan struct{}) func() (interface{}, error) {
+				return func() (interface{}, error) {
+					<-release
+					panic("boom")
+				}
+			},
+			want: func(err error) bool { return err != nil && strings.Contains(err.Error(), "boom") },
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			const callers = 5
+			g := NewGroup()
+			release := make(chan struct{})
+			fn := tt.fn(release)
+
+			errs := make(chan error, callers)
+			for range callers {
+				go func() {
+					_, _, err := g.Do(context.Background(), "key", fn)
+					errs <- err
+				}()
+			}
+			waitCoalesced(t, g, callers-1)
+			close(release)
+			for range callers {
+				if err := <-errs; !tt.want(err) {
+					t.Errorf("error = %v", err)
+				}
+			}
+
+			// a failed call is not kept
+			value, _, err := g.Do(context.Background(), "key", func() (interface{}, error) { return "retried", nil })
+			if err != nil || value != "retried" {
+				t.Errorf("call after failure = %v, %v", value, err)
+			}
+		})
+	}
+}
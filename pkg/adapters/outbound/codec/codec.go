@@ -0,0 +1,152 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeMsgPack  = "application/msgpack"
+)
+
+type (
+	// Codec encodes request and response bodies. Binary codecs produce bytes
+	// that text-only transports must base64 encode.
+	Codec interface {
+		ContentType() string
+		Binary() bool
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	jsonCodec     struct{}
+	protoJSON     struct{}
+	protobufCodec struct{}
+	msgpackCodec  struct{}
+)
+
+var (
+	// JSON is encoding/json, the default codec.
+	JSON Codec = jsonCodec{}
+	// ProtoJSON encodes protobuf messages with protojson, keeping the proto
+	// field names, and any other value with encoding/json.
+	ProtoJSON Codec = protoJSON{}
+	// Protobuf is the protobuf wire format. It only accepts proto.Message.
+	Protobuf Codec = protobufCodec{}
+	// MsgPack is MessagePack, using the json struct tags.
+	MsgPack Codec = msgpackCodec{}
+
+	protoJSONMarshal   = protojson.MarshalOptions{UseProtoNames: true}
+	protoJSONUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+	byContentType = map[string]Codec{
+		ContentTypeJSON:           JSON,
+		ContentTypeProtobuf:       Protobuf,
+		"application/protobuf":    Protobuf,
+		ContentTypeMsgPack:        MsgPack,
+		"application/x-msgpack":   MsgPack,
+		"application/vnd.msgpack": MsgPack,
+	}
+)
+
+// ForContentType returns the codec of a Content-Type header value.
+func ForContentType(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	if c, ok := byContentType[mediaType]; ok {
+		return c, true
+	}
+	if strings.HasSuffix(mediaType, "+json") {
+		return JSON, true
+	}
+	return nil, false
+}
+
+// Negotiate returns the codec of contentType, or fallback when it is unknown.
+// JSON responses are decoded with fallback when fallback is a JSON codec, so
+// ProtoJSON clients keep decoding proto messages with protojson.
+func Negotiate(fallback Codec, contentType string) Codec {
+	c, ok := ForContentType(contentType)
+	if !ok || (c == JSON && !fallback.Binary()) {
+		return fallback
+	}
+	return c
+}
+
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+func (jsonCodec) Binary() bool        { return false }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (protoJSON) ContentType() string { return ContentTypeJSON }
+func (protoJSON) Binary() bool        { return false }
+
+func (protoJSON) Marshal(v interface{}) ([]byte, error) {
+	if message, ok := v.(proto.Message); ok {
+		return protoJSONMarshal.Marshal(message)
+	}
+	return json.Marshal(v)
+}
+
+func (protoJSON) Unmarshal(data []byte, v interface{}) error {
+	if message, ok := v.(proto.Message); ok {
+		return protoJSONUnmarshal.Unmarshal(data, message)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (protobufCodec) ContentType() string { return ContentTypeProtobuf }
+func (protobufCodec) Binary() bool        { return true }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(message)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, message)
+}
+
+func (msgpackCodec) ContentType() string { return ContentTypeMsgPack }
+func (msgpackCodec) Binary() bool        { return true }
+
+// Marshal uses the json struct tags, so types need no msgpack tags.
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := msgpack.NewEncoder(&b)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
@@ -0,0 +1,280 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Strategy unwraps the payload of a successful response and the error of
+	// a failed one. Both work on JSON bodies; others are taken as they are.
+	Strategy interface {
+		Unwrap(body []byte) ([]byte, error)
+		Error(statusCode int, body []byte) error
+	}
+
+	// ResponseError is a failed response, with the message and code found in
+	// its envelope.
+	ResponseError struct {
+		StatusCode int
+		Code       string
+		Message    string
+		Body       []byte
+	}
+
+	raw     struct{}
+	result  struct{}
+	data    struct{}
+	jsonAPI struct{}
+	auto    struct{}
+)
+
+var (
+	// Raw decodes the body itself.
+	Raw Strategy = raw{}
+	// Result unwraps {"code": ..., "content": ...}, see connector.Result.
+	Result Strategy = result{}
+	// Data unwraps {"data": ..., "error": ...}.
+	Data Strategy = data{}
+	// JSONAPI unwraps JSON:API documents, flattening each resource object
+	// into its attributes plus id.
+	JSONAPI Strategy = jsonAPI{}
+	// Auto detects the envelope of each body, falling back to Raw.
+	Auto Strategy = auto{}
+)
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed with status %d", e.StatusCode)
+	}
+	return e.Message
+}
+
+func (raw) Unwrap(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// Error still detects the envelope of error bodies, which backends answering
+// raw payloads often wrap anyway.
+func (raw) Error(statusCode int, body []byte) error {
+	if strategy := detect(body); strategy != Raw {
+		return strategy.Error(statusCode, body)
+	}
+	return rawError(statusCode, body)
+}
+
+func (result) Unwrap(body []byte) ([]byte, error) {
+	var envelope struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	return envelope.Content, nil
+}
+
+func (result) Error(statusCode int, body []byte) error {
+	var envelope struct {
+		Code    json.RawMessage `json:"code"`
+		Content json.RawMessage `json:"content"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || (envelope.Content == nil && envelope.Message == "") {
+		return rawError(statusCode, body)
+	}
+	message := envelope.Message
+	if envelope.Content != nil {
+		message = messageOf(envelope.Content)
+	}
+	return &ResponseError{StatusCode: statusCode, Code: scalar(envelope.Code), Message: message, Body: body}
+}
+
+func (data) Unwrap(body []byte) ([]byte, error) {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	return envelope.Data, nil
+}
+
+func (data) Error(statusCode int, body []byte) error {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error == nil {
+		return rawError(statusCode, body)
+	}
+	var detail struct {
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(envelope.Error, &detail) == nil && detail.Message != "" {
+		return &ResponseError{StatusCode: statusCode, Code: scalar(detail.Code), Message: detail.Message, Body: body}
+	}
+	return &ResponseError{StatusCode: statusCode, Message: messageOf(envelope.Error), Body: body}
+}
+
+func (jsonAPI) Unwrap(body []byte) ([]byte, error) {
+	var document struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(document.Data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var resources []json.RawMessage
+		if err := json.Unmarshal(trimmed, &resources); err != nil {
+			return nil, err
+		}
+		flattened := make([]json.RawMessage, 0, len(resources))
+		for _, resource := range resources {
+			item, err := flatten(resource)
+			if err != nil {
+				return nil, err
+			}
+			flattened = append(flattened, item)
+		}
+		return json.Marshal(flattened)
+	}
+	return flatten(document.Data)
+}
+
+func (jsonAPI) Error(statusCode int, body []byte) error {
+	var document struct {
+		Errors []struct {
+			Status string `json:"status"`
+			Code   string `json:"code"`
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil || len(document.Errors) == 0 {
+		return rawError(statusCode, body)
+	}
+
+	messages := make([]string, 0, len(document.Errors))
+	for _, e := range document.Errors {
+		switch {
+		case e.Title != "" && e.Detail != "":
+			messages = append(messages, e.Title+": "+e.Detail)
+		case e.Detail != "":
+			messages = append(messages, e.Detail)
+		default:
+			messages = append(messages, e.Title)
+		}
+	}
+	return &ResponseError{StatusCode: statusCode, Code: document.Errors[0].Code, Message: strings.Join(messages, "; "), Body: body}
+}
+
+func (auto) Unwrap(body []byte) ([]byte, error) {
+	return detect(body).Unwrap(body)
+}
+
+func (auto) Error(statusCode int, body []byte) error {
+	return detect(body).Error(statusCode, body)
+}
+
+// detect picks the strategy of body from the members of its top level
+// object. Objects with other members, such as connector.ListResponse, are
+// taken as raw payloads.
+func detect(body []byte) Strategy {
+	var members map[string]json.RawMessage
+	if json.Unmarshal(body, &members) != nil {
+		return Raw
+	}
+	has := func(keys ...string) bool {
+		for _, key := range keys {
+			if _, ok := members[key]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	only := func(keys ...string) bool {
+		for member := range members {
+			found := false
+			for _, key := range keys {
+				found = found || member == key
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case only("data", "errors", "meta", "links", "included", "jsonapi") && (has("errors") || has("jsonapi") || isResource(members["data"])):
+		return JSONAPI
+	case only("code", "content", "message") && (has("content") || has("message")):
+		return Result
+	case only("data", "error", "meta") && (has("data") || has("error")):
+		return Data
+	default:
+		return Raw
+	}
+}
+
+func isResource(data json.RawMessage) bool {
+	var resource struct {
+		Type       string          `json:"type"`
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var resources []json.RawMessage
+		if json.Unmarshal(trimmed, &resources) != nil || len(resources) == 0 {
+			return false
+		}
+		trimmed = resources[0]
+	}
+	return json.Unmarshal(trimmed, &resource) == nil && resource.Type != "" && resource.Attributes != nil
+}
+
+// flatten turns a JSON:API resource object into its attributes plus id.
+func flatten(resource json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(resource)) == 0 || string(bytes.TrimSpace(resource)) == "null" {
+		return resource, nil
+	}
+	var object struct {
+		ID         json.RawMessage            `json:"id"`
+		Attributes map[string]json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal(resource, &object); err != nil {
+		return nil, err
+	}
+	fields := object.Attributes
+	if fields == nil {
+		fields = map[string]json.RawMessage{}
+	}
+	if object.ID != nil {
+		fields["id"] = object.ID
+	}
+	return json.Marshal(fields)
+}
+
+func rawError(statusCode int, body []byte) error {
+	return &ResponseError{StatusCode: statusCode, Message: strings.TrimSpace(string(body)), Body: body}
+}
+
+// messageOf returns a JSON string as is and any other value as JSON.
+func messageOf(value json.RawMessage) string {
+	var message string
+	if json.Unmarshal(value, &message) == nil {
+		return message
+	}
+	return string(value)
+}
+
+func scalar(value json.RawMessage) string {
+	if value == nil || string(value) == "null" {
+		return ""
+	}
+	return messageOf(value)
+}
@@ -0,0 +1,162 @@
+package hedge
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDelay    = 100 * time.Millisecond
+	DefaultMaxRatio = 0.1
+
+	// latencies kept to compute the percentile delay, and how many are needed
+	// before it replaces the configured delay
+	window     = 128
+	minSamples = 20
+	// a single hedge can be saved up, so that a burst of slow calls following
+	// many fast ones still hedges no more than MaxRatio of them
+	maxBudget = 1
+	// absorbs the rounding of budgets summed from fractions such as 0.1
+	epsilon = 1e-9
+)
+
+type (
+	// Config tells when to hedge a call. Delay is how long to wait for the
+	// first attempt before sending a second one. With a Percentile, such as
+	// 0.95, the delay follows that percentile of the observed latencies once
+	// enough calls completed. MaxRatio caps the hedges to a fraction of the
+	// calls so that a slow target never receives twice its load.
+	Config struct {
+		Delay      time.Duration
+		Percentile float64
+		MaxRatio   float64
+	}
+
+	// Policy hedges the calls of one client. A nil Policy never hedges.
+	Policy struct {
+		config Config
+
+		mu        sync.Mutex
+		latencies []time.Duration
+		next      int
+		budget    float64
+	}
+
+	result[T any] struct {
+		value T
+		err   error
+	}
+)
+
+func NewPolicy(config Config) *Policy {
+	if config.Delay <= 0 {
+		config.Delay = DefaultDelay
+	}
+	if config.MaxRatio <= 0 {
+		config.MaxRatio = DefaultMaxRatio
+	}
+	config.Percentile = math.Min(config.Percentile, 1)
+	return &Policy{config: config, latencies: make([]time.Duration, 0, window), budget: 1}
+}
+
+// Do calls call and, when it has not returned after the delay of the policy,
+// calls it a second time. The first success is returned and the context of
+// the other attempt is cancelled. When every attempt failed the first error
+// is returned. call must be safe to run twice concurrently.
+func Do[T any](ctx context.Context, p *Policy, call func(ctx context.Context) (T, error)) (T, error) {
+	if p == nil {
+		return call(ctx)
+	}
+	p.request()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result[T], 2)
+	attempt := func() {
+		start := time.Now()
+		value, err := call(ctx)
+		if err == nil {
+			p.observe(time.Since(start))
+		}
+		results <- result[T]{value: value, err: err}
+	}
+	go attempt()
+
+	timer := time.NewTimer(p.delay())
+	defer timer.Stop()
+	hedge := timer.C
+
+	var (
+		zero    T
+		first   error
+		pending = 1
+	)
+	for {
+		select {
+		case <-hedge:
+			hedge = nil
+			if p.allow() {
+				pending++
+				go attempt()
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.value, nil
+			}
+			if first == nil {
+				first = r.err
+			}
+			if pending == 0 {
+				return zero, first
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+func (p *Policy) request() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.budget = math.Min(p.budget+p.config.MaxRatio, maxBudget)
+}
+
+func (p *Policy) allow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.budget < 1-epsilon {
+		return false
+	}
+	p.budget--
+	return true
+}
+
+func (p *Policy) observe(latency time.Duration) {
+	if p.config.Percentile <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.latencies) < window {
+		p.latencies = append(p.latencies, latency)
+		return
+	}
+	p.latencies[p.next] = latency
+	p.next = (p.next + 1) % window
+}
+
+func (p *Policy) delay() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.Percentile <= 0 || len(p.latencies) < minSamples {
+		return p.config.Delay
+	}
+	sorted := slices.Clone(p.latencies)
+	slices.Sort(sorted)
+	return sorted[int(p.config.Percentile*float64(len(sorted)-1))]
+}
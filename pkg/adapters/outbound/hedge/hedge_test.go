@@ -0,0 +1,156 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowCall counts its attempts, each of them taking latency unless cancelled.
+func slowCall(attempts *atomic.Int32, latency time.Duration) func(ctx context.Context) (int32, error) {
+	return func(ctx context.Context) (int32, error) {
+		n := attempts.Add(1)
+		select {
+		case <-time.After(latency):
+			return n, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func TestDelayFollowsThePercentile(t *testing.T) {
+	p := NewPolicy(Config{Delay: time.Hour, Percentile: 0.9})
+
+	for i := 1; i < minSamples; i++ {
+		p.observe(time.Duration(i) * time.Millisecond)
+	}
+	if got := p.delay(); got != time.Hour {
+		t.Fatalf("delay with %d samples = %v, want the configured delay", minSamples-1, got)
+	}
+
+	p.observe(minSamples * time.Millisecond)
+	// 0.9 of the way through 1ms..20ms
+	if got, want := p.delay(), 18*time.Millisecond; got != want {
+		t.Fatalf("delay = %v, want %v", got, want)
+	}
+
+	// only the last window latencies count
+	for range window {
+		p.observe(time.Second)
+	}
+	if got := p.delay(); got != time.Second {
+		t.Fatalf("delay after a full window = %v, want 1s", got)
+	}
+}
+
+func TestDoHedgesOnce(t *testing.T) {
+	p := NewPolicy(Config{Delay: time.Millisecond, MaxRatio: 1})
+
+	var attempts atomic.Int32
+	if _, err := Do(context.Background(), p, slowCall(&attempts, 50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("%d attempts, want the call and one hedge", n)
+	}
+
+	attempts.Store(0)
+	if _, err := Do(context.Background(), p, slowCall(&attempts, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("%d attempts of a fast call, want 1", n)
+	}
+}
+
+func TestDoWaitsForEveryAttemptToFail(t *testing.T) {
+	p := NewPolicy(Config{Delay: time.Millisecond, MaxRatio: 1})
+
+	var attempts atomic.Int32
+	hedge := errors.New("hedge")
+	_, err := Do(context.Background(), p, func(ctx context.Context) (int, error) {
+		if attempts.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return 0, errors.New("call")
+		}
+		return 0, hedge
+	})
+	// the hedge failed first
+	if !errors.Is(err, hedge) || attempts.Load() != 2 {
+		t.Fatalf("error = %v after %d attempts, want the hedge error after 2", err, attempts.Load())
+	}
+}
+
+func TestBudgetRefillsWithTheRatio(t *testing.T) {
+	p := NewPolicy(Config{Delay: time.Millisecond, MaxRatio: 0.25})
+
+	var attempts atomic.Int32
+	hedged := func() bool {
+		attempts.Store(0)
+		if _, err := Do(context.Background(), p, slowCall(&attempts, 10*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+		return attempts.Load() == 2
+	}
+
+	if !hedged() {
+		t.Fatal("first slow call was not hedged")
+	}
+	// the budget spent, 4 calls at 0.25 earn the next hedge
+	for i := range 3 {
+		if hedged() {
+			t.Fatalf("call %d hedged before the budget refilled", i+2)
+		}
+	}
+	if !hedged() {
+		t.Fatal("call 5 was not hedged once the budget refilled")
+	}
+}
+
+func TestRatioCapsABurstAfterFastCalls(t *testing.T) {
+	const (
+		ratio = 0.1
+		fast  = 500
+		burst = 50
+	)
+	p := NewPolicy(Config{Delay: 5 * time.Millisecond, MaxRatio: ratio})
+
+	var attempts atomic.Int32
+	for range fast {
+		if _, err := Do(context.Background(), p, slowCall(&attempts, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	attempts.Store(0)
+
+	var wg sync.WaitGroup
+	for range burst {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Do(context.Background(), p, slowCall(&attempts, 50*time.Millisecond)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	hedges := int(attempts.Load()) - burst
+	if limit := 1 + int(burst*ratio); hedges > limit {
+		t.Fatalf("%d hedges for %d calls, want at most %d", hedges, burst, limit)
+	}
+}
+
+func TestNilPolicyNeverHedges(t *testing.T) {
+	var attempts atomic.Int32
+	if _, err := Do(context.Background(), nil, slowCall(&attempts, 10*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("%d attempts, want 1", n)
+	}
+}
@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultThrottlePause is how long a limiter pauses after a throttling
+	// signal without Retry-After.
+	DefaultThrottlePause = time.Second
+
+	minFactor      = 0.1
+	recoveryFactor = 0.05
+)
+
+var ErrRateLimited = errors.New("rate limited")
+
+type (
+	// Config limits the calls to a target. Rate is in calls per second and
+	// zero leaves the rate unlimited, as a zero MaxInFlight does for
+	// concurrency. FailFast returns ErrRateLimited instead of waiting.
+	Config struct {
+		Rate        float64
+		Burst       int
+		MaxInFlight int
+		FailFast    bool
+	}
+
+	// Limiter is a token bucket combined with a max-in-flight semaphore.
+	// Throttling signals halve its rate and pause it, successes slowly bring
+	// it back. A nil Limiter does not limit anything.
+	Limiter struct {
+		target string
+
+		mu          sync.Mutex
+		config      Config
+		tokens      float64
+		last        time.Time
+		factor      float64
+		pausedUntil time.Time
+		slots       chan struct{}
+	}
+)
+
+func NewLimiter(target string, config Config) *Limiter {
+	l := &Limiter{target: target, factor: 1, last: time.Now()}
+	l.configure(config)
+	l.tokens = float64(l.burst())
+	return l
+}
+
+// Acquire waits for a slot and a token, under ctx. The returned function
+// releases the slot and must be called once the call completed.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	slots, failFast := l.slots, l.config.FailFast
+	l.mu.Unlock()
+
+	release := func() {}
+	if slots != nil {
+		if err := l.acquireSlot(ctx, slots, failFast); err != nil {
+			return nil, err
+		}
+		release = func() { <-slots }
+	}
+
+	for {
+		wait := l.reserve(time.Now())
+		if wait == 0 {
+			return release, nil
+		}
+		if failFast {
+			release()
+			return nil, l.rateLimited()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Throttled slows the limiter down after the target rejected a call,
+// pausing it for retryAfter, or DefaultThrottlePause when unknown.
+func (l *Limiter) Throttled(retryAfter time.Duration) {
+	if l == nil {
+		return
+	}
+	if retryAfter <= 0 {
+		retryAfter = DefaultThrottlePause
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.factor = math.Max(l.factor/2, minFactor)
+	l.tokens = 0
+	if until := time.Now().Add(retryAfter); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// Succeeded lets a slowed down limiter recover towards its configured rate.
+func (l *Limiter) Succeeded() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.factor = math.Min(l.factor+recoveryFactor, 1)
+}
+
+// Observe reports the outcome of a call: throttling errors slow the limiter
+// down, other outcomes let it recover.
+func (l *Limiter) Observe(err error) {
+	if delay, ok := ThrottleDelay(err); ok {
+		l.Throttled(delay)
+		return
+	}
+	if err == nil {
+		l.Succeeded()
+	}
+}
+
+// ObserveStatus is Observe for HTTP responses: 429 and 503 are throttling
+// signals, honouring their Retry-After header.
+func (l *Limiter) ObserveStatus(statusCode int, retryAfter string) {
+	switch {
+	case statusCode == 429 || statusCode == 503:
+		l.Throttled(ParseRetryAfter(retryAfter))
+	case statusCode < 500:
+		l.Succeeded()
+	}
+}
+
+func (l *Limiter) acquireSlot(ctx context.Context, slots chan struct{}, failFast bool) error {
+	if failFast {
+		select {
+		case slots <- struct{}{}:
+			return nil
+		default:
+			return l.rateLimited()
+		}
+	}
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, returning how long to wait when none is left.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.config.Rate <= 0 {
+		return 0
+	}
+
+	rate := l.config.Rate * l.factor
+	l.tokens = math.Min(l.tokens+now.Sub(l.last).Seconds()*rate, float64(l.burst()))
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / rate * float64(time.Second))
+}
+
+func (l *Limiter) configure(config Config) {
+	if config.MaxInFlight != l.config.MaxInFlight || l.slots == nil {
+		l.slots = nil
+		if config.MaxInFlight > 0 {
+			l.slots = make(chan struct{}, config.MaxInFlight)
+		}
+	}
+	l.config = config
+}
+
+func (l *Limiter) burst() int {
+	if l.config.Burst > 0 {
+		return l.config.Burst
+	}
+	return int(math.Max(math.Ceil(l.config.Rate), 1))
+}
+
+func (l *Limiter) rateLimited() error {
+	return fmt.Errorf("%w: %s", ErrRateLimited, l.target)
+}
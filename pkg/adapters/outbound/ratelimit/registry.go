@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/smithy-go"
+)
+
+type (
+	// Registry holds the limiters of every target, shared by all the clients
+	// using it. Targets are hosts, function names, queue URLs or topic ARNs.
+	Registry struct {
+		mu       sync.RWMutex
+		limiters map[string]*Limiter
+		parent   *Registry
+	}
+)
+
+// Default is the registry of clients that were not given another one.
+var Default = NewRegistry()
+
+var throttlingCodes = map[string]bool{
+	"Throttling":                    true,
+	"ThrottlingException":           true,
+	"ThrottledException":            true,
+	"RequestThrottled":              true,
+	"RequestThrottledException":     true,
+	"TooManyRequestsException":      true,
+	"RequestLimitExceeded":          true,
+	"ProvisionedThroughputExceeded": true,
+}
+
+func NewRegistry() *Registry {
+	return &Registry{limiters: map[string]*Limiter{}}
+}
+
+// Derive returns a registry of its own limiters, falling back to those of r
+// for the targets it does not configure.
+func (r *Registry) Derive() *Registry {
+	derived := NewRegistry()
+	derived.parent = r
+	return derived
+}
+
+// Configure sets the limits of target. The limiter of a target already
+// configured is updated in place, so clients keep sharing it.
+func (r *Registry) Configure(target string, config Config) *Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limiter, ok := r.limiters[target]; ok {
+		limiter.mu.Lock()
+		if limiter.config != config {
+			limiter.configure(config)
+		}
+		limiter.mu.Unlock()
+		return limiter
+	}
+	limiter := NewLimiter(target, config)
+	r.limiters[target] = limiter
+	return limiter
+}
+
+// For returns the limiter of target, nil when it has no limits.
+func (r *Registry) For(target string) *Limiter {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	limiter, ok := r.limiters[target]
+	r.mu.RUnlock()
+	if !ok {
+		return r.parent.For(target)
+	}
+	return limiter
+}
+
+func (r *Registry) Remove(target string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.limiters, target)
+}
+
+// Configure sets the limits of target in the Default registry.
+func Configure(target string, config Config) *Limiter {
+	return Default.Configure(target, config)
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date, returning zero when it is missing or invalid.
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// ThrottleDelay reports whether err is an AWS throttling error, with the
+// delay it asks for when it has one.
+func ThrottleDelay(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var tooManyRequests *types.TooManyRequestsException
+	if errors.As(err, &tooManyRequests) {
+		return ParseRetryAfter(aws.ToString(tooManyRequests.RetryAfterSeconds)), true
+	}
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) && throttlingCodes[apiError.ErrorCode()] {
+		return 0, true
+	}
+	return 0, false
+}
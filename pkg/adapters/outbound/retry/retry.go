@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 100 * time.Millisecond
+	DefaultMaxDelay    = 5 * time.Second
+)
+
+type (
+	// Config bounds the retries of a call. Attempts wait an exponential,
+	// jittered backoff starting at BaseDelay, or longer when the target asked
+	// for it, but never more than MaxDelay.
+	Config struct {
+		MaxAttempts int
+		BaseDelay   time.Duration
+		MaxDelay    time.Duration
+	}
+
+	// Policy retries the calls of one client. A nil Policy never retries.
+	Policy struct {
+		config Config
+	}
+
+	// Classifier tells whether the outcome of an attempt is worth retrying
+	// and the delay the target asked for, zero when it did not.
+	Classifier[T any] func(value T, err error) (bool, time.Duration)
+)
+
+func NewPolicy(config Config) *Policy {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = DefaultBaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultMaxDelay
+	}
+	return &Policy{config: config}
+}
+
+// Do calls call until classify rejects its outcome or the attempts of the
+// policy run out, returning the last outcome. Callers only retry calls that
+// are safe to repeat: idempotent ones, or mutating ones sent with the same
+// idempotency key on every attempt.
+func Do[T any](ctx context.Context, p *Policy, call func(ctx context.Context) (T, error), classify Classifier[T]) (T, error) {
+	if p == nil {
+		return call(ctx)
+	}
+	for attempt := 1; ; attempt++ {
+		value, err := call(ctx)
+		retry, after := classify(value, err)
+		if !retry || attempt >= p.config.MaxAttempts || after > p.config.MaxDelay {
+			return value, err
+		}
+
+		timer := time.NewTimer(max(p.backoff(attempt), after))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return value, err
+		}
+	}
+}
+
+// backoff is a full jitter exponential backoff.
+func (p *Policy) backoff(attempt int) time.Duration {
+	ceiling := p.config.MaxDelay
+	if shift := attempt - 1; shift < 16 {
+		ceiling = min(p.config.BaseDelay<<shift, p.config.MaxDelay)
+	}
+	return rand.N(ceiling) + 1
+}
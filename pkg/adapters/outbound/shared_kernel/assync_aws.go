@@ -1,8 +1,99 @@
 package shared_kernel
 
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	"github.com/tecmise/connector-lib/pkg/ports/output/logging"
+)
+
+// The message attribute describing how a queue or topic message body is
+// encoded: its content type, with the content-encoding and
+// content-transfer-encoding parameters of compressed and binary bodies.
+const (
+	AttributeContentType      = "content-type"
+	AttributeTransferEncoding = "content-transfer-encoding"
+	TransferEncodingBase64    = "base64"
+
+	// MaxMessageAttributes is the number of attributes SQS and SNS accept on
+	// a message.
+	MaxMessageAttributes = 10
+)
+
+var ErrTooManyAttributes = errors.New("too many message attributes")
+
 type (
 	FifoProperties struct {
 		MessageGroupId         string
 		MessageDeduplicationId string
 	}
+
+	// EncodedMessage is a message body ready for SQS or SNS, with the
+	// attributes consumers need to decode it.
+	EncodedMessage struct {
+		Body        string
+		Attributes  map[string]string
+		contentType string
+		binary      bool
+		size        int
+	}
 )
+
+// EncodeMessage marshals message with the codec of the options, compressing
+// it when configured. Binary bodies are base64 encoded since SQS and SNS only
+// carry text. The encoding is described by a single content-type attribute,
+// only added when it differs from what DecodeMessage assumes. attributes is
+// the number of other attributes of the message: when they leave no room for
+// the content-type attribute, the body is not compressed.
+func (o Options) EncodeMessage(message interface{}, attributes int) (*EncodedMessage, error) {
+	data, err := o.Codec.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := &EncodedMessage{
+		Body:        string(data),
+		Attributes:  map[string]string{},
+		contentType: o.Codec.ContentType(),
+		size:        len(data),
+	}
+	params := map[string]string{}
+	described := encoded.contentType != codec.JSON.ContentType() || o.Codec.Binary()
+	if o.MessageCompression.Applies(len(data)) && (described || attributes < MaxMessageAttributes) {
+		if data, err = Compress(o.MessageCompression.Encoding, data); err != nil {
+			return nil, err
+		}
+		params[AttributeContentEncoding] = o.MessageCompression.Encoding
+	}
+	if o.Codec.Binary() || len(params) > 0 {
+		encoded.Body, encoded.binary = base64.StdEncoding.EncodeToString(data), true
+		params[AttributeTransferEncoding] = TransferEncodingBase64
+	}
+	if described || len(params) > 0 {
+		encoded.Attributes[AttributeContentType] = mime.FormatMediaType(encoded.contentType, params)
+	}
+	return encoded, nil
+}
+
+// CheckMessageAttributes fails when a message carries more attributes than
+// SQS and SNS accept.
+func CheckMessageAttributes(count int) error {
+	if count > MaxMessageAttributes {
+		return fmt.Errorf("%w: %d, at most %d", ErrTooManyAttributes, count, MaxMessageAttributes)
+	}
+	return nil
+}
+
+// Loggable returns the body as it should be logged, built only when the line
+// is emitted.
+func (m *EncodedMessage) Loggable(o Options) logging.Lazy {
+	return func() string {
+		if m.binary {
+			return fmt.Sprintf("[%s, %d bytes, %d sent]", m.contentType, m.size, len(m.Body))
+		}
+		return o.Redactor.Body([]byte(m.Body))
+	}
+}
@@ -0,0 +1,84 @@
+package shared_kernel
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	type message struct {
+		Name string `json:"name" msgpack:"name"`
+	}
+	compress := WithMessageCompression(EncodingGzip, 1)
+	tests := []struct {
+		name        string
+		opts        []Option
+		others      int
+		contentType string
+		compressed  bool
+	}{
+		{"json", nil, 2, "", false},
+		{"compressed json", []Option{compress}, 2, "application/json; content-encoding=gzip; content-transfer-encoding=base64", true},
+		{"compressed json with room for one attribute", []Option{compress}, MaxMessageAttributes - 1, "application/json; content-encoding=gzip; content-transfer-encoding=base64", true},
+		{"compressed json without room", []Option{compress}, MaxMessageAttributes, "", false},
+		{"msgpack", []Option{WithCodec(codec.MsgPack)}, 2, "application/msgpack; content-transfer-encoding=base64", false},
+		{"compressed msgpack without room", []Option{WithCodec(codec.MsgPack), compress}, MaxMessageAttributes, "application/msgpack; content-encoding=gzip; content-transfer-encoding=base64", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := NewOptions(tt.opts...).EncodeMessage(message{Name: strings.Repeat("a", 64)}, tt.others)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(encoded.Attributes) > 1 || encoded.Attributes[AttributeContentType] != tt.contentType {
+				t.Fatalf("attributes = %v, want content-type %q only", encoded.Attributes, tt.contentType)
+			}
+			if tt.contentType == "" {
+				if err := CheckMessageAttributes(tt.others + len(encoded.Attributes)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if compressed := strings.Contains(encoded.Attributes[AttributeContentType], EncodingGzip); compressed != tt.compressed {
+				t.Fatalf("compressed = %v, want %v", compressed, tt.compressed)
+			}
+
+			var decoded message
+			if err := DecodeMessage(encoded.Body, encoded.Attributes, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Name != strings.Repeat("a", 64) {
+				t.Fatalf("decoded %+v", decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeMessageOfSeparateAttributes(t *testing.T) {
+	data, err := Compress(EncodingGzip, []byte(`{"name":"a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Name string `json:"name"`
+	}
+	err = DecodeMessage(base64.StdEncoding.EncodeToString(data), map[string]string{
+		AttributeContentEncoding:  EncodingGzip,
+		AttributeTransferEncoding: TransferEncodingBase64,
+	}, &decoded)
+	if err != nil || decoded.Name != "a" {
+		t.Fatalf("decoded %+v, %v", decoded, err)
+	}
+}
+
+func TestCheckMessageAttributes(t *testing.T) {
+	if err := CheckMessageAttributes(MaxMessageAttributes); err != nil {
+		t.Fatalf("%d attributes: %v", MaxMessageAttributes, err)
+	}
+	if err := CheckMessageAttributes(MaxMessageAttributes + 1); !errors.Is(err, ErrTooManyAttributes) {
+		t.Fatalf("%d attributes: %v, want ErrTooManyAttributes", MaxMessageAttributes+1, err)
+	}
+}
@@ -0,0 +1,192 @@
+package shared_kernel
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+)
+
+const (
+	ContentTypeJSON        = "application/json"
+	ContentTypeOctetStream = "application/octet-stream"
+)
+
+type (
+	// EncodedBody is a request body ready to be sent. Binary bodies must be
+	// base64 encoded by transports that only carry text. Streamed bodies have
+	// a Stream of Size bytes instead of Data, of unknown size when Size is not
+	// positive, see Buffer.
+	EncodedBody struct {
+		Data        []byte
+		Stream      io.ReadCloser
+		Size        int64
+		ContentType string
+		Binary      bool
+	}
+)
+
+// methodsWithBody is the single source of truth of which methods carry a
+// body, shared by every transport so that switching SCHOOL_CONNECTOR_TYPE
+// never changes what the backend receives.
+var methodsWithBody = map[string]bool{
+	http.MethodGet:     false,
+	http.MethodHead:    false,
+	http.MethodOptions: false,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+}
+
+func HasBody(method string) bool {
+	return methodsWithBody[strings.ToUpper(method)]
+}
+
+// EncodeRequest encodes body for a request with method. It returns nil when
+// the method does not carry a body.
+func EncodeRequest(method string, body interface{}) (*EncodedBody, error) {
+	return EncodeRequestWith(codec.JSON, method, body)
+}
+
+// EncodeRequestWith is EncodeRequest marshaling values with c.
+func EncodeRequestWith(c codec.Codec, method string, body interface{}) (*EncodedBody, error) {
+	if !HasBody(method) {
+		return nil, nil
+	}
+	return EncodeBodyWith(c, body)
+}
+
+// EncodeRequest encodes body with the codec of the options. Streamed bodies
+// are buffered when the options sign requests, since the signature covers the
+// hash of the payload.
+func (o Options) EncodeRequest(method string, body interface{}) (*EncodedBody, error) {
+	encoded, err := EncodeRequestWith(o.Codec, method, body)
+	if err != nil || encoded == nil || o.Signer == nil {
+		return encoded, err
+	}
+	return encoded, encoded.Buffer()
+}
+
+// EncodeBody encodes body according to its type: raw bytes, readers and
+// lambda.Binary are sent as is, multipart forms are written with a fresh
+// boundary and anything else is marshaled as JSON.
+func EncodeBody(body interface{}) (*EncodedBody, error) {
+	return EncodeBodyWith(codec.JSON, body)
+}
+
+// EncodeBodyWith is EncodeBody marshaling values with c.
+func EncodeBodyWith(c codec.Codec, body interface{}) (*EncodedBody, error) {
+	switch v := deref(body).(type) {
+	case nil:
+		return &EncodedBody{ContentType: c.ContentType(), Binary: c.Binary()}, nil
+	case []byte:
+		return &EncodedBody{Data: v, ContentType: ContentTypeOctetStream, Binary: true}, nil
+	case *[]byte:
+		if v == nil {
+			return &EncodedBody{ContentType: ContentTypeOctetStream, Binary: true}, nil
+		}
+		return &EncodedBody{Data: *v, ContentType: ContentTypeOctetStream, Binary: true}, nil
+	case lambda2.Binary:
+		return encodeBinary(v), nil
+	case *lambda2.Binary:
+		if v == nil {
+			return &EncodedBody{ContentType: ContentTypeOctetStream, Binary: true}, nil
+		}
+		return encodeBinary(*v), nil
+	case *multipart.Form:
+		return encodeMultipart(NewMultipart().Form(v)), nil
+	case *Multipart:
+		return encodeMultipart(v), nil
+	case io.Reader:
+		return encodeReader(v), nil
+	default:
+		data, err := c.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request body: %w", err)
+		}
+		return &EncodedBody{Data: data, ContentType: c.ContentType(), Binary: c.Binary()}, nil
+	}
+}
+
+// EncodeMultipart writes form as a multipart/form-data body held in memory.
+// Each file is closed as soon as it has been copied.
+func EncodeMultipart(form *multipart.Form) (*EncodedBody, error) {
+	encoded := encodeMultipart(NewMultipart().Form(form))
+	if err := encoded.Buffer(); err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
+func encodeMultipart(m *Multipart) *EncodedBody {
+	return &EncodedBody{Stream: m.Reader(), ContentType: m.ContentType(), Binary: true}
+}
+
+// Buffer reads a streamed body into Data, for transports that can not send
+// a stream.
+func (e *EncodedBody) Buffer() error {
+	if e.Stream == nil {
+		return nil
+	}
+	defer e.Stream.Close()
+	data, err := io.ReadAll(e.Stream)
+	if err != nil {
+		return fmt.Errorf("error reading request body: %w", err)
+	}
+	e.Data, e.Stream, e.Size = data, nil, 0
+	return nil
+}
+
+func (e *EncodedBody) IsMultipart() bool {
+	return strings.HasPrefix(e.ContentType, "multipart/")
+}
+
+// deref unwraps the pointers the generic clients pass as body, *T, when T is
+// an interface such as io.Reader, a pointer, []byte or string, so that the
+// value is encoded according to its own type.
+func deref(body interface{}) interface{} {
+	value := reflect.ValueOf(body)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		switch value.Elem().Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.String:
+		case reflect.Slice:
+			if value.Elem().Type().Elem().Kind() != reflect.Uint8 {
+				return body
+			}
+		default:
+			return body
+		}
+		body = value.Elem().Interface()
+		value = reflect.ValueOf(body)
+	}
+	return body
+}
+
+// encodeReader streams r, announcing its length when it knows it, as
+// bytes.Reader, strings.Reader and bytes.Buffer do.
+func encodeReader(r io.Reader) *EncodedBody {
+	encoded := &EncodedBody{ContentType: ContentTypeOctetStream, Binary: true}
+	if l, ok := r.(interface{ Len() int }); ok {
+		encoded.Size = int64(l.Len())
+	}
+	if rc, ok := r.(io.ReadCloser); ok {
+		encoded.Stream = rc
+	} else {
+		encoded.Stream = io.NopCloser(r)
+	}
+	return encoded
+}
+
+func encodeBinary(b lambda2.Binary) *EncodedBody {
+	contentType := b.ContentType
+	if contentType == "" {
+		contentType = ContentTypeOctetStream
+	}
+	return &EncodedBody{Data: b.Data, ContentType: contentType, Binary: true}
+}
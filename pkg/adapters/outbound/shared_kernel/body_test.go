@@ -0,0 +1,169 @@
+package shared_kernel
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+)
+
+func TestEncodeBodyKinds(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+	var (
+		raw        = []byte{0x00, 0xff, 0x10}
+		reader     io.Reader
+		readCloser io.ReadCloser
+		text       = "hello"
+		binary     = lambda2.Binary{Data: []byte("%PDF"), ContentType: "application/pdf"}
+		value      = payload{Name: "a"}
+	)
+	var anything, nilAny interface{}
+	anything = []byte("any")
+	newReader := func() { reader = bytes.NewReader(raw) }
+	newReadCloser := func() { readCloser = io.NopCloser(strings.NewReader("closer")) }
+
+	tests := []struct {
+		name        string
+		body        func() interface{}
+		contentType string
+		binary      bool
+		data        string
+	}{
+		{"nil", func() interface{} { return nil }, ContentTypeJSON, false, ""},
+		{"bytes", func() interface{} { return raw }, ContentTypeOctetStream, true, string(raw)},
+		{"pointer to bytes", func() interface{} { return &raw }, ContentTypeOctetStream, true, string(raw)},
+		{"reader", func() interface{} { newReader(); return reader }, ContentTypeOctetStream, true, string(raw)},
+		{"pointer to reader", func() interface{} { newReader(); return &reader }, ContentTypeOctetStream, true, string(raw)},
+		{"pointer to read closer", func() interface{} { newReadCloser(); return &readCloser }, ContentTypeOctetStream, true, "closer"},
+		{"pointer to interface", func() interface{} { return &anything }, ContentTypeOctetStream, true, "any"},
+		{"pointer to nil interface", func() interface{} { return &nilAny }, ContentTypeJSON, false, ""},
+		{"string", func() interface{} { return text }, ContentTypeJSON, false, `"hello"`},
+		{"pointer to string", func() interface{} { return &text }, ContentTypeJSON, false, `"hello"`},
+		{"binary", func() interface{} { return binary }, "application/pdf", true, "%PDF"},
+		{"pointer to binary", func() interface{} { return &binary }, "application/pdf", true, "%PDF"},
+		{"struct", func() interface{} { return value }, ContentTypeJSON, false, `{"name":"a"}`},
+		{"pointer to struct", func() interface{} { return &value }, ContentTypeJSON, false, `{"name":"a"}`},
+		{"map", func() interface{} { return map[string]int{"a": 1} }, ContentTypeJSON, false, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeBodyWith(codec.JSON, tt.body())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := encoded.Buffer(); err != nil {
+				t.Fatal(err)
+			}
+			if encoded.ContentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", encoded.ContentType, tt.contentType)
+			}
+			if encoded.Binary != tt.binary {
+				t.Errorf("binary = %v, want %v", encoded.Binary, tt.binary)
+			}
+			if string(encoded.Data) != tt.data {
+				t.Errorf("data = %q, want %q", encoded.Data, tt.data)
+			}
+		})
+	}
+}
+
+func TestEncodeBodyStreamsReaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader io.Reader
+		size   int64
+	}{
+		{"known length", strings.NewReader("content"), 7},
+		{"unknown length", io.MultiReader(strings.NewReader("content")), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeBodyWith(codec.JSON, tt.reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoded.Stream == nil || encoded.Data != nil {
+				t.Fatalf("reader not streamed: %+v", encoded)
+			}
+			if encoded.Size != tt.size {
+				t.Errorf("size = %d, want %d", encoded.Size, tt.size)
+			}
+			if err := encoded.Buffer(); err != nil || string(encoded.Data) != "content" {
+				t.Errorf("buffered %q, %v", encoded.Data, err)
+			}
+		})
+	}
+}
+
+func TestEncodeBodyMultipart(t *testing.T) {
+	builder := NewMultipart().Field("kind", "report").File("file", "report.txt", "text/plain", strings.NewReader("content"))
+
+	// a form as parsed by a server, whose files are opened from its headers
+	source, err := EncodeBodyWith(codec.JSON, NewMultipart().Field("kind", "report").File("file", "report.txt", "text/plain", strings.NewReader("content")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sourceParams, _ := mime.ParseMediaType(source.ContentType)
+	form, err := multipart.NewReader(source.Stream, sourceParams["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer form.RemoveAll()
+
+	for name, body := range map[string]interface{}{"builder": builder, "form": form} {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := EncodeBodyWith(codec.JSON, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoded.Stream == nil {
+				t.Fatal("multipart body not streamed")
+			}
+			if err := encoded.Buffer(); err != nil {
+				t.Fatal(err)
+			}
+			mediaType, params, err := mime.ParseMediaType(encoded.ContentType)
+			if err != nil || mediaType != "multipart/form-data" || !encoded.Binary {
+				t.Fatalf("content type = %q, binary = %v", encoded.ContentType, encoded.Binary)
+			}
+			parsed, err := multipart.NewReader(bytes.NewReader(encoded.Data), params["boundary"]).ReadForm(1 << 20)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parsed.Value["kind"]; len(got) != 1 || got[0] != "report" {
+				t.Errorf("kind = %v", got)
+			}
+			files := parsed.File["file"]
+			if len(files) != 1 || files[0].Filename != "report.txt" || files[0].Header.Get("Content-Type") != "text/plain" {
+				t.Fatalf("files = %v", files)
+			}
+			file, err := files[0].Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			if content, _ := io.ReadAll(file); string(content) != "content" {
+				t.Errorf("file content = %q", content)
+			}
+		})
+	}
+}
+
+func TestEncodeRequestWithoutBody(t *testing.T) {
+	encoded, err := EncodeRequest("GET", map[string]string{"ignored": "yes"})
+	if err != nil || encoded != nil {
+		t.Fatalf("GET encoded %v, %v", encoded, err)
+	}
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		if encoded, err := EncodeRequest(method, map[string]string{}); err != nil || encoded == nil {
+			t.Errorf("%s encoded %v, %v", method, encoded, err)
+		}
+	}
+}
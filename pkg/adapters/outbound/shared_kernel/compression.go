@@ -0,0 +1,133 @@
+package shared_kernel
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+
+	// AcceptEncoding lists the response encodings REST clients decompress.
+	AcceptEncoding = "gzip, br, zstd"
+
+	// AttributeContentEncoding is the parameter of the content-type message
+	// attribute flagging compressed queue and topic messages.
+	AttributeContentEncoding = "content-encoding"
+
+	// DefaultCompressionThreshold is the smallest body worth compressing.
+	DefaultCompressionThreshold = 1024
+)
+
+type (
+	// Compression compresses bodies of at least MinSize bytes with Encoding.
+	// The zero value disables compression.
+	Compression struct {
+		Encoding string
+		MinSize  int
+	}
+)
+
+// Compress encodes data with encoding, one of gzip, br and zstd.
+func Compress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		return fasthttp.AppendGzipBytes(nil, data), nil
+	case EncodingBrotli:
+		return fasthttp.AppendBrotliBytes(nil, data), nil
+	case EncodingZstd:
+		return fasthttp.AppendZstdBytes(nil, data), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// Decompress decodes data compressed with encoding. An empty encoding
+// returns data as is.
+func Decompress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return data, nil
+	case EncodingGzip:
+		return fasthttp.AppendGunzipBytes(nil, data)
+	case EncodingBrotli:
+		return fasthttp.AppendUnbrotliBytes(nil, data)
+	case EncodingZstd:
+		return fasthttp.AppendUnzstdBytes(nil, data)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// Applies reports whether a body of size bytes must be compressed.
+func (c Compression) Applies(size int) bool {
+	return c.Encoding != "" && size >= c.MinSize
+}
+
+// WithRequestCompression compresses REST request bodies of at least minSize
+// bytes with encoding, setting Content-Encoding. Only use it with backends
+// that accept compressed requests.
+func WithRequestCompression(encoding string, minSize int) Option {
+	return func(o *Options) {
+		o.RequestCompression = Compression{Encoding: encoding, MinSize: minSize}
+	}
+}
+
+// WithoutResponseCompression stops REST clients from asking for compressed
+// responses.
+func WithoutResponseCompression() Option {
+	return func(o *Options) {
+		o.DisableResponseCompression = true
+	}
+}
+
+// WithMessageCompression compresses SQS and SNS bodies of at least minSize
+// bytes with encoding. They are sent base64 encoded and flagged by the
+// content-type attribute, see DecodeMessage. Messages whose other attributes
+// leave no room for it are sent uncompressed.
+func WithMessageCompression(encoding string, minSize int) Option {
+	return func(o *Options) {
+		o.MessageCompression = Compression{Encoding: encoding, MinSize: minSize}
+	}
+}
+
+// DecodeMessage decodes a message published by the SQS or SNS publishers
+// from its body and string attributes.
+func DecodeMessage(body string, attributes map[string]string, v interface{}) error {
+	contentType, params, err := mime.ParseMediaType(attributes[AttributeContentType])
+	if err != nil {
+		contentType, params = codec.JSON.ContentType(), map[string]string{}
+	}
+	// messages of earlier versions carried each parameter as an attribute
+	for _, name := range []string{AttributeContentEncoding, AttributeTransferEncoding} {
+		if params[name] == "" {
+			params[name] = attributes[name]
+		}
+	}
+
+	data := []byte(body)
+	if params[AttributeTransferEncoding] == TransferEncodingBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return fmt.Errorf("decoding base64 message: %w", err)
+		}
+		data = decoded
+	}
+
+	data, err = Decompress(params[AttributeContentEncoding], data)
+	if err != nil {
+		return err
+	}
+
+	c, ok := codec.ForContentType(contentType)
+	if !ok {
+		c = codec.JSON
+	}
+	return c.Unmarshal(data, v)
+}
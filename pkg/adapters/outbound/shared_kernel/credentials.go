@@ -0,0 +1,40 @@
+package shared_kernel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ApplyCredentials returns a copy of headers completed with the credentials
+// resolved for ctx. Headers set explicitly by the caller take precedence.
+func (o Options) ApplyCredentials(ctx context.Context, headers map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(headers)+2)
+	for key, value := range headers {
+		result[key] = value
+	}
+
+	creds, err := o.Credentials.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("resolving credentials: %w", err)
+	}
+	for key, value := range creds.Headers() {
+		if !hasHeader(result, key) {
+			result[key] = value
+		}
+	}
+	// a signer adds Authorization once every other header is set
+	if o.Signer == nil && !hasHeader(result, "Authorization") && !hasHeader(result, "x-api-key") {
+		o.Logger.DebugContext(ctx, "no credentials resolved for outbound call")
+	}
+	return result, nil
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) && value != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package shared_kernel
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/sigv4"
+)
+
+func TestApplyCredentialsWarnsNothing(t *testing.T) {
+	tests := map[string][]Option{
+		"signed":   {WithSigV4("us-east-1", sigv4.ServiceAPIGateway)},
+		"unsigned": nil,
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			options := NewOptions(append(opts, WithLogger(logger))...)
+
+			if _, err := options.ApplyCredentials(context.Background(), nil); err != nil {
+				t.Fatal(err)
+			}
+			logged := out.String()
+			if strings.Contains(logged, "level=WARN") {
+				t.Fatalf("warned: %s", logged)
+			}
+			if missing := strings.Contains(logged, "no credentials resolved"); missing != (options.Signer == nil) {
+				t.Fatalf("logged %q", logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package shared_kernel
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpproxy"
+)
+
+// maxBufferedStreamBody is the largest response a streaming client reads in
+// full before handing it to the caller.
+const maxBufferedStreamBody = 64 << 10
+
+type (
+	// HTTPConfig configures the fasthttp client of a REST connector. Timeout
+	// bounds a whole request, the other timeouts a single step of it. For
+	// streamed downloads Timeout only bounds the wait for the response
+	// headers and ReadTimeout the wait between two reads of the body.
+	HTTPConfig struct {
+		Timeout             time.Duration
+		DialTimeout         time.Duration
+		ReadTimeout         time.Duration
+		WriteTimeout        time.Duration
+		MaxConnsPerHost     int
+		MaxIdleConnDuration time.Duration
+		TLSConfig           *tls.Config
+		Proxy               string
+	}
+
+	// idleConn replaces the read deadline fasthttp sets for a whole response,
+	// which would cut a streamed body read at the pace of the caller, by an
+	// idle timeout renewed before every read.
+	idleConn struct {
+		net.Conn
+		timeout time.Duration
+	}
+)
+
+var (
+	defaultHTTPClient   = DefaultHTTPConfig().NewClient(false)
+	defaultStreamClient = DefaultHTTPConfig().NewClient(true)
+
+	// clients holds the clients built for each configuration, and the
+	// streaming copies of injected clients, so that options resolved for
+	// every call, as by Call, share one connection pool.
+	clients sync.Map
+	// derivedTLS holds the TLS configurations derived by WithRootCAs and
+	// WithClientCertificate, so that equal options give an equal HTTPConfig.
+	derivedTLS sync.Map
+)
+
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:             30 * time.Second,
+		DialTimeout:         5 * time.Second,
+		ReadTimeout:         30 * time.Second,
+		WriteTimeout:        30 * time.Second,
+		MaxConnsPerHost:     fasthttp.DefaultMaxConnsPerHost,
+		MaxIdleConnDuration: fasthttp.DefaultMaxIdleConnDuration,
+	}
+}
+
+// Clients returns the client and the streaming client built from c, built
+// once for all the options with the same configuration.
+func (c HTTPConfig) Clients() (*fasthttp.Client, *fasthttp.Client) {
+	// Timeout is applied per request, not by the clients
+	key := c
+	key.Timeout = 0
+	built, _ := clients.Load(key)
+	if built == nil {
+		built, _ = clients.LoadOrStore(key, [2]*fasthttp.Client{c.NewClient(false), c.NewClient(true)})
+	}
+	pair := built.([2]*fasthttp.Client)
+	return pair[0], pair[1]
+}
+
+// NewClient builds a client from c. A streaming client hands response
+// bodies larger than 64 KB to the caller as a stream instead of reading them.
+func (c HTTPConfig) NewClient(stream bool) *fasthttp.Client {
+	client := &fasthttp.Client{
+		ReadTimeout:         c.ReadTimeout,
+		WriteTimeout:        c.WriteTimeout,
+		MaxConnsPerHost:     c.MaxConnsPerHost,
+		MaxIdleConnDuration: c.MaxIdleConnDuration,
+		TLSConfig:           c.TLSConfig,
+	}
+	if stream {
+		client.StreamResponseBody = true
+		client.MaxResponseBodySize = maxBufferedStreamBody
+	}
+	if c.Proxy != "" {
+		client.Dial = fasthttpproxy.FasthttpHTTPDialerTimeout(strings.TrimPrefix(strings.TrimPrefix(c.Proxy, "http://"), "https://"), c.DialTimeout)
+	} else if c.DialTimeout > 0 {
+		timeout := c.DialTimeout
+		client.Dial = func(addr string) (net.Conn, error) {
+			return fasthttp.DialTimeout(addr, timeout)
+		}
+	}
+	if stream {
+		client.Dial = idleDial(client.Dial, c.ReadTimeout)
+	}
+	return client
+}
+
+// idleDial wraps the connections of dial, fasthttp.Dial when nil, in an
+// idleConn.
+func idleDial(dial fasthttp.DialFunc, timeout time.Duration) fasthttp.DialFunc {
+	if dial == nil {
+		dial = fasthttp.Dial
+	}
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &idleConn{Conn: conn, timeout: timeout}, nil
+	}
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	if c.timeout > 0 {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *idleConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *idleConn) SetDeadline(t time.Time) error {
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// WithHTTPClient sends REST requests through client, ignoring the other HTTP
+// options but WithTimeout and WithBaseURL. Downloads go through a streaming
+// copy of client, since client itself reads whole responses into memory.
+func WithHTTPClient(client *fasthttp.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+		stream, _ := clients.Load(client)
+		if stream == nil {
+			stream, _ = clients.LoadOrStore(client, streamingClient(client))
+		}
+		o.StreamClient = stream.(*fasthttp.Client)
+	}
+}
+
+// streamingClient builds a streaming client with the settings of client, its
+// ReadTimeout bounding the wait between two reads of a body.
+func streamingClient(client *fasthttp.Client) *fasthttp.Client {
+	stream := &fasthttp.Client{
+		Transport:                     client.Transport,
+		TLSConfig:                     client.TLSConfig,
+		RetryIf:                       client.RetryIf,
+		RetryIfErr:                    client.RetryIfErr,
+		ConfigureClient:               client.ConfigureClient,
+		Name:                          client.Name,
+		MaxConnsPerHost:               client.MaxConnsPerHost,
+		MaxIdleConnDuration:           client.MaxIdleConnDuration,
+		MaxConnDuration:               client.MaxConnDuration,
+		MaxIdemponentCallAttempts:     client.MaxIdemponentCallAttempts,
+		ReadBufferSize:                client.ReadBufferSize,
+		WriteBufferSize:               client.WriteBufferSize,
+		ReadTimeout:                   client.ReadTimeout,
+		WriteTimeout:                  client.WriteTimeout,
+		MaxConnWaitTimeout:            client.MaxConnWaitTimeout,
+		ConnPoolStrategy:              client.ConnPoolStrategy,
+		NoDefaultUserAgentHeader:      client.NoDefaultUserAgentHeader,
+		DialDualStack:                 client.DialDualStack,
+		DisableHeaderNamesNormalizing: client.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        client.DisablePathNormalizing,
+		StreamResponseBody:            true,
+		MaxResponseBodySize:           maxBufferedStreamBody,
+	}
+	dial := client.Dial
+	if dialTimeout := client.DialTimeout; dialTimeout != nil {
+		dial = func(addr string) (net.Conn, error) {
+			return dialTimeout(addr, fasthttp.DefaultDialTimeout)
+		}
+	}
+	stream.Dial = idleDial(dial, client.ReadTimeout)
+	return stream
+}
+
+// WithTimeout bounds every REST request, 30s by default. Zero disables it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.HTTP.Timeout = timeout
+	}
+}
+
+func WithDialTimeout(timeout time.Duration) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.DialTimeout = timeout
+	})
+}
+
+func WithReadTimeout(timeout time.Duration) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.ReadTimeout = timeout
+	})
+}
+
+func WithWriteTimeout(timeout time.Duration) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.WriteTimeout = timeout
+	})
+}
+
+func WithMaxConnsPerHost(conns int) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.MaxConnsPerHost = conns
+	})
+}
+
+// WithMaxIdleConnDuration closes keep-alive connections idle for longer than
+// duration.
+func WithMaxIdleConnDuration(duration time.Duration) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.MaxIdleConnDuration = duration
+	})
+}
+
+func WithTLSConfig(config *tls.Config) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.TLSConfig = config
+	})
+}
+
+// WithRootCAs trusts pool instead of the system roots, e.g. for a private CA.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.TLSConfig = deriveTLS(c.TLSConfig, pool, "", func(config *tls.Config) {
+			config.RootCAs = pool
+		})
+	})
+}
+
+// WithClientCertificate presents certificate to servers requiring mTLS.
+func WithClientCertificate(certificate tls.Certificate) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.TLSConfig = deriveTLS(c.TLSConfig, nil, fingerprint(certificate), func(config *tls.Config) {
+			config.Certificates = append(config.Certificates, certificate)
+		})
+	})
+}
+
+// WithProxy sends requests through the HTTP proxy at address, given as
+// host:port with optional user:password@ credentials.
+func WithProxy(address string) Option {
+	return withHTTP(func(c *HTTPConfig) {
+		c.Proxy = address
+	})
+}
+
+// WithBaseURL prefixes the host of every request that is not an absolute URL.
+func WithBaseURL(baseURL string) Option {
+	return func(o *Options) {
+		o.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// URL joins the base URL, host and resource of a request.
+func (o Options) URL(host, resource string) string {
+	if o.BaseURL == "" || strings.Contains(host, "://") {
+		return host + "/" + resource
+	}
+	if host = strings.Trim(host, "/"); host == "" {
+		return o.BaseURL + "/" + resource
+	}
+	return o.BaseURL + "/" + host + "/" + resource
+}
+
+func withHTTP(configure func(*HTTPConfig)) Option {
+	return func(o *Options) {
+		configure(&o.HTTP)
+		o.httpConfigured = true
+	}
+}
+
+// deriveTLS returns a copy of base changed by apply, the same copy for the
+// same base, root CAs and certificate.
+func deriveTLS(base *tls.Config, pool *x509.CertPool, certificate string, apply func(*tls.Config)) *tls.Config {
+	key := struct {
+		base        *tls.Config
+		pool        *x509.CertPool
+		certificate string
+	}{base, pool, certificate}
+	if derived, ok := derivedTLS.Load(key); ok {
+		return derived.(*tls.Config)
+	}
+	config := cloneTLS(base)
+	apply(config)
+	derived, _ := derivedTLS.LoadOrStore(key, config)
+	return derived.(*tls.Config)
+}
+
+func fingerprint(certificate tls.Certificate) string {
+	hash := sha256.New()
+	for _, der := range certificate.Certificate {
+		hash.Write(der)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func cloneTLS(config *tls.Config) *tls.Config {
+	if config == nil {
+		return &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return config.Clone()
+}
@@ -0,0 +1,52 @@
+package shared_kernel
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/sigv4"
+	"github.com/valyala/fasthttp"
+)
+
+func TestWithHTTPClientStreamsDownloads(t *testing.T) {
+	client := &fasthttp.Client{Name: "injected", ReadTimeout: 5 * time.Second, MaxConnsPerHost: 7}
+	options := NewOptions(WithHTTPClient(client))
+
+	if options.HTTPClient != client {
+		t.Fatal("the injected client is not used for requests")
+	}
+	stream := options.StreamClient
+	if stream == client || !stream.StreamResponseBody || stream.MaxResponseBodySize != maxBufferedStreamBody {
+		t.Fatalf("downloads do not go through a streaming client: %+v", stream)
+	}
+	if stream.Name != client.Name || stream.ReadTimeout != client.ReadTimeout || stream.MaxConnsPerHost != client.MaxConnsPerHost {
+		t.Errorf("the streaming client does not keep the settings of the injected client: %+v", stream)
+	}
+	if client.StreamResponseBody || client.Dial != nil {
+		t.Error("the injected client was modified")
+	}
+}
+
+func TestEqualOptionsShareTheirClients(t *testing.T) {
+	pool := x509.NewCertPool()
+	opts := func() []Option {
+		return []Option{WithReadTimeout(5 * time.Second), WithRootCAs(pool), WithSigV4("us-east-1", sigv4.ServiceAPIGateway)}
+	}
+	first, second := NewOptions(opts()...), NewOptions(append(opts(), WithTimeout(time.Second))...)
+
+	if first.HTTPClient != second.HTTPClient || first.StreamClient != second.StreamClient {
+		t.Error("equal HTTP options built new clients")
+	}
+	if first.Signer != second.Signer {
+		t.Error("equal SigV4 options built new signers")
+	}
+	if other := NewOptions(WithReadTimeout(6 * time.Second)); other.HTTPClient == first.HTTPClient {
+		t.Error("different HTTP options share a client")
+	}
+
+	injected := &fasthttp.Client{}
+	if NewOptions(WithHTTPClient(injected)).StreamClient != NewOptions(WithHTTPClient(injected)).StreamClient {
+		t.Error("the streaming copy of an injected client is built on every call")
+	}
+}
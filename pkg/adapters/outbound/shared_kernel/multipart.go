@@ -0,0 +1,148 @@
+package shared_kernel
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+type (
+	// Multipart is a multipart/form-data body written while it is sent, so
+	// files are never held in memory. Sources implementing io.Closer are
+	// closed once copied, or when the body is abandoned.
+	Multipart struct {
+		boundary string
+		parts    []multipartPart
+	}
+
+	multipartPart struct {
+		field       string
+		filename    string
+		contentType string
+		value       string
+		source      io.Reader
+		// open opens the source of a file only when it is written
+		open func() (io.Reader, error)
+	}
+)
+
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(io.Discard).Boundary()}
+}
+
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{field: name, value: value})
+	return m
+}
+
+// File adds a file read from source. An empty contentType is sent as
+// application/octet-stream.
+func (m *Multipart) File(field, filename, contentType string, source io.Reader) *Multipart {
+	if contentType == "" {
+		contentType = ContentTypeOctetStream
+	}
+	m.parts = append(m.parts, multipartPart{field: field, filename: filename, contentType: contentType, source: source})
+	return m
+}
+
+// Form adds the values and files of form. Each file is opened when it is
+// written and closed once copied.
+func (m *Multipart) Form(form *multipart.Form) *Multipart {
+	if form == nil {
+		return m
+	}
+	for name, values := range form.Value {
+		for _, value := range values {
+			m.Field(name, value)
+		}
+	}
+	for field, files := range form.File {
+		for _, file := range files {
+			contentType := file.Header.Get("Content-Type")
+			if contentType == "" {
+				contentType = ContentTypeOctetStream
+			}
+			m.parts = append(m.parts, multipartPart{field: field, filename: file.Filename, contentType: contentType, open: func() (io.Reader, error) {
+				return file.Open()
+			}})
+		}
+	}
+	return m
+}
+
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// Reader returns the body. It is written by a goroutine as it is read, which
+// only exits once the body was read to the end or the reader closed: callers
+// must Close it, closing it early also closes the remaining files.
+func (m *Multipart) Reader() io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(m.writeTo(writer))
+	}()
+	return reader
+}
+
+func (m *Multipart) writeTo(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for i, part := range m.parts {
+		if err := writePart(writer, part); err != nil {
+			closeSources(m.parts[i+1:])
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func writePart(writer *multipart.Writer, part multipartPart) error {
+	if part.open != nil {
+		source, err := part.open()
+		if err != nil {
+			return fmt.Errorf("error opening file %s: %w", part.field, err)
+		}
+		part.source = source
+	}
+	if part.source == nil {
+		if err := writer.WriteField(part.field, part.value); err != nil {
+			return fmt.Errorf("error writing form field %s: %w", part.field, err)
+		}
+		return nil
+	}
+	if closer, ok := part.source.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(part.field), escapeQuotes(part.filename)))
+	header.Set("Content-Type", part.contentType)
+	fileWriter, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("error creating form file %s: %w", part.field, err)
+	}
+	if _, err := io.Copy(fileWriter, part.source); err != nil {
+		return fmt.Errorf("error copying file %s: %w", part.field, err)
+	}
+	return nil
+}
+
+func closeSources(parts []multipartPart) {
+	for _, part := range parts {
+		if closer, ok := part.source.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
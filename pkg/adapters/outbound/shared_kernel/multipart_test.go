@@ -0,0 +1,54 @@
+package shared_kernel
+
+import (
+	"io"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// endless is a file that never ends, recording whether it was closed.
+type endless struct {
+	closed atomic.Bool
+}
+
+func (e *endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func (e *endless) Close() error {
+	e.closed.Store(true)
+	return nil
+}
+
+func TestClosingAnAbandonedMultipartStopsItsWriter(t *testing.T) {
+	for name, read := range map[string]int{"unread": 0, "partly read": 4096} {
+		t.Run(name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+
+			file, next := &endless{}, &endless{}
+			reader := NewMultipart().Field("kind", "report").
+				File("file", "a.bin", "", file).
+				File("next", "b.bin", "", next).
+				Reader()
+			if _, err := io.ReadFull(reader, make([]byte, read)); err != nil {
+				t.Fatal(err)
+			}
+			if err := reader.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > before || !file.closed.Load() || !next.closed.Load() {
+				if time.Now().After(deadline) {
+					t.Fatalf("%d goroutines, %d before; files closed %v, %v", runtime.NumGoroutine(), before, file.closed.Load(), next.closed.Load())
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package shared_kernel
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/cache"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/coalesce"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/codec"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/envelope"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/hedge"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/ratelimit"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/retry"
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/sigv4"
+	"github.com/tecmise/connector-lib/pkg/ports/output/connector"
+	"github.com/tecmise/connector-lib/pkg/ports/output/credentials"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
+	lambda2 "github.com/tecmise/connector-lib/pkg/ports/output/lambda"
+	"github.com/tecmise/connector-lib/pkg/ports/output/logging"
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	Options struct {
+		Logger         *slog.Logger
+		Redactor       *logging.Redactor
+		Cache          *cache.Cache
+		Coalescer      *coalesce.Group
+		CursorStrategy connector.CursorStrategy
+		Credentials    credentials.Provider
+		Signer         *sigv4.Signer
+		PayloadVersion lambda2.PayloadVersion
+		Stage          string
+		HTTP           HTTPConfig
+		HTTPClient     *fasthttp.Client
+		StreamClient   *fasthttp.Client
+		BaseURL        string
+		Codec          codec.Codec
+		Envelope       envelope.Strategy
+		Limiters       *ratelimit.Registry
+		Hedge          *hedge.Policy
+		Retry          *retry.Policy
+		// IdempotencyKeys generates a key for mutating calls without one
+		IdempotencyKeys bool
+
+		RequestCompression         Compression
+		MessageCompression         Compression
+		DisableResponseCompression bool
+
+		httpConfigured bool
+		ownLimiters    bool
+	}
+
+	Option func(*Options)
+)
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Logger:         logging.Default(),
+		Redactor:       logging.DefaultRedactor(),
+		CursorStrategy: connector.DefaultCursorStrategy(),
+		Credentials:    credentials.FromContext(),
+		PayloadVersion: lambda2.PayloadVersion1,
+		HTTP:           DefaultHTTPConfig(),
+		Codec:          codec.JSON,
+		Limiters:       ratelimit.Default,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	switch {
+	case options.HTTPClient != nil:
+	case options.httpConfigured:
+		options.HTTPClient, options.StreamClient = options.HTTP.Clients()
+	default:
+		options.HTTPClient = defaultHTTPClient
+		options.StreamClient = defaultStreamClient
+	}
+	return options
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		if logger != nil {
+			o.Logger = logger
+		}
+	}
+}
+
+func WithLogHandler(handler slog.Handler) Option {
+	return func(o *Options) {
+		if handler != nil {
+			o.Logger = slog.New(handler)
+		}
+	}
+}
+
+// WithRedactedHeaders masks the given headers, in addition to the defaults,
+// whenever a request or response is logged.
+func WithRedactedHeaders(headers ...string) Option {
+	return func(o *Options) {
+		o.Redactor = o.Redactor.WithHeaders(headers...)
+	}
+}
+
+// WithRedactedFields masks the given JSON body fields, in addition to the
+// defaults, whenever a body is logged.
+func WithRedactedFields(fields ...string) Option {
+	return func(o *Options) {
+		o.Redactor = o.Redactor.WithFields(fields...)
+	}
+}
+
+// WithMaxLoggedBody truncates logged bodies to size bytes.
+func WithMaxLoggedBody(size int) Option {
+	return func(o *Options) {
+		o.Redactor = o.Redactor.WithMaxBodySize(size)
+	}
+}
+
+// WithCache serves GET requests from c. Nil disables caching.
+func WithCache(c *cache.Cache) Option {
+	return func(o *Options) {
+		o.Cache = c
+	}
+}
+
+// WithCoalescing makes concurrent identical GETs share a single call through
+// group. Group.Stats reports how many calls were saved.
+func WithCoalescing(group *coalesce.Group) Option {
+	return func(o *Options) {
+		o.Coalescer = group
+	}
+}
+
+// WithCursorStrategy sets how a cursor stored with connector.WithCursor is
+// sent to the backend.
+func WithCursorStrategy(strategy connector.CursorStrategy) Option {
+	return func(o *Options) {
+		o.CursorStrategy = strategy
+	}
+}
+
+// WithCredentials resolves the credentials of every call through provider
+// instead of reading them from the caller's context.
+func WithCredentials(provider credentials.Provider) Option {
+	return func(o *Options) {
+		if provider != nil {
+			o.Credentials = provider
+		}
+	}
+}
+
+// WithTokenSource sends the tokens of source as the Authorization header. The
+// API key is still taken from the caller's context.
+func WithTokenSource(source credentials.TokenSource) Option {
+	return func(o *Options) {
+		o.Credentials = credentials.ProviderFunc(func(ctx context.Context) (credentials.Credentials, error) {
+			token, err := source.AccessToken(ctx)
+			if err != nil {
+				return credentials.Credentials{}, err
+			}
+			apiKey, _ := credentials.APIKey(ctx)
+			return credentials.Credentials{BearerToken: token, APIKey: apiKey}, nil
+		})
+	}
+}
+
+// WithSigV4 signs REST requests with AWS SigV4 using the default credentials
+// chain, e.g. WithSigV4("us-east-1", sigv4.ServiceLambda) for Function URLs.
+// Without opts the signer, and so the loaded credentials, is shared by every
+// client of the region and service; with opts, build the options once.
+func WithSigV4(region, service string, opts ...sigv4.Option) Option {
+	return func(o *Options) {
+		if len(opts) == 0 {
+			o.Signer = sigv4.Shared(region, service)
+			return
+		}
+		o.Signer = sigv4.New(region, service, opts...)
+	}
+}
+
+// WithPayloadVersion selects the API Gateway event format emulated by the
+// Lambda-proxy clients, lambda.PayloadVersion1 by default.
+func WithPayloadVersion(version lambda2.PayloadVersion) Option {
+	return func(o *Options) {
+		o.PayloadVersion = version
+	}
+}
+
+// WithStage sets the stage reported in the emulated API Gateway events.
+func WithStage(stage string) Option {
+	return func(o *Options) {
+		o.Stage = stage
+	}
+}
+
+// WithCodec encodes bodies with c, codec.JSON by default. REST clients also
+// ask for it through Accept and decode responses by their Content-Type.
+func WithCodec(c codec.Codec) Option {
+	return func(o *Options) {
+		if c != nil {
+			o.Codec = c
+		}
+	}
+}
+
+// WithEnvelope sets how response payloads and errors are unwrapped, e.g.
+// envelope.Auto. Clients decode raw payloads by default.
+func WithEnvelope(strategy envelope.Strategy) Option {
+	return func(o *Options) {
+		o.Envelope = strategy
+	}
+}
+
+// WithRateLimiters takes the limiters of every target from registry instead
+// of ratelimit.Default. WithRateLimit then configures registry, so that the
+// limits are shared by every client given it.
+func WithRateLimiters(registry *ratelimit.Registry) Option {
+	return func(o *Options) {
+		o.Limiters = registry
+		o.ownLimiters = true
+	}
+}
+
+// WithRateLimit limits the calls to target, a host, function name, queue URL
+// or topic ARN. The limit only applies to this client unless WithRateLimiters
+// shares a registry; options resolved on every call, as by client_rest.Call,
+// must share one to be limited at all.
+func WithRateLimit(target string, config ratelimit.Config) Option {
+	return func(o *Options) {
+		switch {
+		case o.Limiters == nil:
+			o.Limiters = ratelimit.NewRegistry()
+		case !o.ownLimiters:
+			o.Limiters = o.Limiters.Derive()
+		}
+		o.ownLimiters = true
+		o.Limiters.Configure(target, config)
+	}
+}
+
+// WithHedging sends a second identical request when a GET is slower than
+// config allows, keeping the first success. Only idempotent reads are hedged.
+func WithHedging(config hedge.Config) Option {
+	return func(o *Options) {
+		o.Hedge = hedge.NewPolicy(config)
+	}
+}
+
+// WithRetry retries failed and throttled calls. Mutating calls are only
+// retried when they carry an idempotency key, see WithIdempotencyKeys.
+func WithRetry(config retry.Config) Option {
+	return func(o *Options) {
+		o.Retry = retry.NewPolicy(config)
+	}
+}
+
+// WithIdempotencyKeys generates an idempotency key for every mutating call
+// whose context does not carry one.
+func WithIdempotencyKeys() Option {
+	return func(o *Options) {
+		o.IdempotencyKeys = true
+	}
+}
+
+// IdempotencyKey returns the key of the operation in ctx, or a new one when
+// the options generate keys.
+func (o Options) IdempotencyKey(ctx context.Context) string {
+	if key, ok := idempotency.FromContext(ctx); ok {
+		return key
+	}
+	if o.IdempotencyKeys {
+		return idempotency.NewKey()
+	}
+	return ""
+}
+
+// ApplyIdempotencyKey adds the idempotency key of the operation to the
+// headers of a mutating call, unless the caller set one explicitly.
+func (o Options) ApplyIdempotencyKey(ctx context.Context, method string, headers map[string]string) map[string]string {
+	if !idempotency.Mutating(method) || idempotency.Of(headers) != "" {
+		return headers
+	}
+	return idempotency.Apply(headers, o.IdempotencyKey(ctx))
+}
+
+// Decode unmarshals data with the codec matching contentType, falling back to
+// the codec of the options, after unwrapping its envelope. An empty payload
+// leaves v untouched.
+func (o Options) Decode(contentType string, data []byte, v interface{}) error {
+	c := codec.Negotiate(o.Codec, contentType)
+	if o.Envelope != nil && !c.Binary() {
+		payload, err := o.Envelope.Unwrap(data)
+		if err != nil {
+			return err
+		}
+		if len(payload) == 0 {
+			return nil
+		}
+		data = payload
+	}
+	return c.Unmarshal(data, v)
+}
+
+// DecodeError returns the error of a failed response, decoded with the
+// envelope of the options. Without one the envelope is detected.
+func (o Options) DecodeError(statusCode int, body []byte) error {
+	if o.Envelope == nil {
+		return envelope.Raw.Error(statusCode, body)
+	}
+	return o.Envelope.Error(statusCode, body)
+}
@@ -0,0 +1,39 @@
+package shared_kernel
+
+import (
+	"testing"
+
+	"github.com/tecmise/connector-lib/pkg/adapters/outbound/ratelimit"
+)
+
+func TestWithRateLimitKeepsLimitsToTheClient(t *testing.T) {
+	limit := ratelimit.Config{Rate: 1, Burst: 1}
+	limited := NewOptions(WithRateLimit("api.example.com", limit))
+
+	if limited.Limiters.For("api.example.com") == nil {
+		t.Fatal("the client is not limited")
+	}
+	if ratelimit.Default.For("api.example.com") != nil {
+		t.Fatal("the limit was set process-wide")
+	}
+	if NewOptions().Limiters.For("api.example.com") != nil {
+		t.Fatal("a client without limits is limited")
+	}
+
+	shared := ratelimit.NewRegistry()
+	first := NewOptions(WithRateLimiters(shared), WithRateLimit("queue", limit))
+	second := NewOptions(WithRateLimiters(shared))
+	if first.Limiters.For("queue") == nil || first.Limiters.For("queue") != second.Limiters.For("queue") {
+		t.Fatal("clients sharing a registry do not share its limits")
+	}
+}
+
+func TestWithRateLimitFallsBackToTheDefaultRegistry(t *testing.T) {
+	global := ratelimit.Configure("global.example.com", ratelimit.Config{Rate: 1, Burst: 1})
+	defer ratelimit.Default.Remove("global.example.com")
+
+	options := NewOptions(WithRateLimit("api.example.com", ratelimit.Config{Rate: 1, Burst: 1}))
+	if options.Limiters.For("global.example.com") != global {
+		t.Fatal("the limits configured process-wide are lost")
+	}
+}
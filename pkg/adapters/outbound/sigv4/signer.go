@@ -0,0 +1,138 @@
+package sigv4
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	ServiceAPIGateway = "execute-api"
+	ServiceLambda     = "lambda"
+
+	// UnsignedPayload is used as payload hash when the body is streamed and
+	// cannot be hashed before being sent.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+type (
+	// Signer signs fasthttp requests with AWS Signature Version 4, for
+	// IAM-authorized API Gateway endpoints and Lambda Function URLs.
+	Signer struct {
+		region  string
+		service string
+		signer  *v4.Signer
+
+		once        sync.Once
+		credentials aws.CredentialsProvider
+		err         error
+	}
+
+	Option func(*Signer)
+)
+
+var shared sync.Map
+
+// New returns a signer using the default aws-sdk-go-v2 credentials chain,
+// loaded on first use.
+func New(region, service string, opts ...Option) *Signer {
+	s := &Signer{
+		region:  region,
+		service: service,
+		signer:  v4.NewSigner(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Shared returns the signer of region and service using the default
+// credentials chain, loaded once for the whole process.
+func Shared(region, service string) *Signer {
+	key := region + "/" + service
+	if signer, ok := shared.Load(key); ok {
+		return signer.(*Signer)
+	}
+	signer, _ := shared.LoadOrStore(key, New(region, service))
+	return signer.(*Signer)
+}
+
+// WithCredentialsProvider replaces the default credentials chain.
+func WithCredentialsProvider(provider aws.CredentialsProvider) Option {
+	return func(s *Signer) {
+		s.once.Do(func() {
+			s.credentials = provider
+		})
+	}
+}
+
+// Sign adds the Authorization, X-Amz-Date and, for temporary credentials,
+// X-Amz-Security-Token headers to req. It must run after every other header
+// and the body are set. Streamed bodies are signed as UNSIGNED-PAYLOAD, which
+// API Gateway rejects: buffer them before signing for execute-api.
+func (s *Signer) Sign(ctx context.Context, req *fasthttp.Request) error {
+	payloadHash := UnsignedPayload
+	if req.IsBodyStream() && s.service == ServiceAPIGateway {
+		return fmt.Errorf("signing request: %s requires the payload hash, streamed bodies must be buffered", ServiceAPIGateway)
+	}
+	if !req.IsBodyStream() {
+		sum := sha256.Sum256(req.Body())
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	return s.SignWithPayloadHash(ctx, req, payloadHash)
+}
+
+func (s *Signer) SignWithPayloadHash(ctx context.Context, req *fasthttp.Request, payloadHash string) error {
+	provider, err := s.provider(ctx)
+	if err != nil {
+		return err
+	}
+	creds, err := provider.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving aws credentials: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, string(req.Header.Method()), req.URI().String(), nil)
+	if err != nil {
+		return fmt.Errorf("building request to sign: %w", err)
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		httpReq.Header.Add(string(key), string(value))
+	})
+	if payloadHash == UnsignedPayload {
+		httpReq.Header.Set("X-Amz-Content-Sha256", UnsignedPayload)
+	}
+
+	if err := s.signer.SignHTTP(ctx, creds, httpReq, payloadHash, s.service, s.region, time.Now()); err != nil {
+		return fmt.Errorf("signing request: %w", err)
+	}
+
+	for _, name := range []string{"Authorization", "X-Amz-Date", "X-Amz-Security-Token", "X-Amz-Content-Sha256"} {
+		if value := httpReq.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return nil
+}
+
+func (s *Signer) provider(ctx context.Context) (aws.CredentialsProvider, error) {
+	s.once.Do(func() {
+		cfg, err := config.LoadDefaultConfig(context.WithoutCancel(ctx), config.WithRegion(s.region))
+		if err != nil {
+			s.err = fmt.Errorf("loading aws configuration: %w", err)
+			return
+		}
+		s.credentials = cfg.Credentials
+	})
+	return s.credentials, s.err
+}
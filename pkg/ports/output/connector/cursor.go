@@ -0,0 +1,99 @@
+package connector
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+const (
+	CursorInQuery CursorLocation = iota
+	CursorInHeader
+
+	DefaultCursorName = "cursor"
+)
+
+type (
+	CursorResponse[T any] struct {
+		Data       []T    `json:"data"`
+		NextCursor string `json:"nextCursor,omitempty"`
+		PrevCursor string `json:"prevCursor,omitempty"`
+	}
+
+	// CursorRequest is the cursor based counterpart of rest.FindAll.
+	CursorRequest struct {
+		Cursor string `json:"cursor,omitempty"`
+		Limit  int32  `json:"limit,omitempty"`
+		Filter string `json:"filter,omitempty"`
+		Order  string `json:"order,omitempty"`
+	}
+
+	CursorLocation int
+
+	// CursorStrategy tells the transports where the backend expects the
+	// cursor: a query parameter or a header named Name.
+	CursorStrategy struct {
+		In   CursorLocation
+		Name string
+	}
+
+	cursorContextKey struct{}
+)
+
+func DefaultCursorStrategy() CursorStrategy {
+	return CursorStrategy{In: CursorInQuery, Name: DefaultCursorName}
+}
+
+// WithCursor stores the cursor of the next call in ctx. The v2 clients apply
+// it according to their cursor strategy.
+func WithCursor(ctx context.Context, cursor string) context.Context {
+	return context.WithValue(ctx, cursorContextKey{}, cursor)
+}
+
+func CursorFromContext(ctx context.Context) (string, bool) {
+	cursor, ok := ctx.Value(cursorContextKey{}).(string)
+	return cursor, ok && cursor != ""
+}
+
+// Apply returns resource and headers carrying cursor, replacing the cursor
+// resource may already carry. Headers is copied before being modified.
+func (s CursorStrategy) Apply(resource string, headers map[string]string, cursor string) (string, map[string]string) {
+	if cursor == "" {
+		return resource, headers
+	}
+	name := s.name()
+
+	if s.In == CursorInHeader {
+		copied := make(map[string]string, len(headers)+1)
+		for k, v := range headers {
+			copied[k] = v
+		}
+		copied[name] = cursor
+		return resource, copied
+	}
+
+	path, rawQuery, _ := strings.Cut(resource, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		query = url.Values{}
+	}
+	query.Set(name, cursor)
+	return path + "?" + query.Encode(), headers
+}
+
+// Header returns the name of the header carrying the cursor, or "" when it is
+// sent in the query. Caches must vary on it, the resource is the same for
+// every page.
+func (s CursorStrategy) Header() string {
+	if s.In != CursorInHeader {
+		return ""
+	}
+	return s.name()
+}
+
+func (s CursorStrategy) name() string {
+	if s.Name == "" {
+		return DefaultCursorName
+	}
+	return s.Name
+}
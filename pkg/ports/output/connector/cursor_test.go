@@ -0,0 +1,29 @@
+package connector
+
+import "testing"
+
+func TestCursorStrategyApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy CursorStrategy
+		resource string
+		want     string
+		header   string
+	}{
+		{"query", DefaultCursorStrategy(), "items", "items?cursor=next", ""},
+		{"query with parameters", DefaultCursorStrategy(), "items?limit=2", "items?cursor=next&limit=2", ""},
+		{"query replacing a cursor", DefaultCursorStrategy(), "items?cursor=first&limit=2", "items?cursor=next&limit=2", ""},
+		{"header", CursorStrategy{In: CursorInHeader, Name: "X-Cursor"}, "items?limit=2", "items?limit=2", "next"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, headers := tt.strategy.Apply(tt.resource, map[string]string{}, "next")
+			if resource != tt.want {
+				t.Errorf("resource = %q, want %q", resource, tt.want)
+			}
+			if got := headers[tt.strategy.Header()]; tt.header != "" && got != tt.header {
+				t.Errorf("header %q = %q, want %q", tt.strategy.Header(), got, tt.header)
+			}
+		})
+	}
+}
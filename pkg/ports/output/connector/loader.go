@@ -0,0 +1,211 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLoaderWait     = 2 * time.Millisecond
+	DefaultLoaderMaxBatch = 100
+)
+
+var ErrNotFound = errors.New("not found")
+
+type (
+	// NotFoundError is returned by Loader.Load when the batched list response
+	// did not contain the requested id.
+	NotFoundError struct {
+		ID int64
+	}
+
+	// Loader turns concurrent Find(id) calls into a single List call. It is
+	// meant to live for the scope of one request: results are memoized.
+	Loader[T any] struct {
+		call     Call[T]
+		build    func(ids []int64) Parameter
+		id       func(T) int64
+		wait     time.Duration
+		maxBatch int
+
+		mu      sync.Mutex
+		batch   *loaderBatch[T]
+		results map[int64]*loaderBatch[T]
+	}
+
+	LoaderOption func(*loaderConfig)
+
+	loaderConfig struct {
+		wait     time.Duration
+		maxBatch int
+	}
+
+	loaderBatch[T any] struct {
+		ids   []int64
+		done  chan struct{}
+		items map[int64]T
+		err   error
+	}
+)
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("id %d not found", e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// WithLoaderWait sets how long the loader collects ids before dispatching.
+func WithLoaderWait(wait time.Duration) LoaderOption {
+	return func(c *loaderConfig) {
+		c.wait = wait
+	}
+}
+
+// WithLoaderMaxBatch dispatches a batch as soon as it holds size ids.
+func WithLoaderMaxBatch(size int) LoaderOption {
+	return func(c *loaderConfig) {
+		c.maxBatch = size
+	}
+}
+
+// NewLoader builds a loader issuing call.List with the parameter returned by
+// build, typically a rest.FindOnes body or an ids query, and matching items
+// back to callers through id.
+func NewLoader[T any](call Call[T], build func(ids []int64) Parameter, id func(T) int64, opts ...LoaderOption) *Loader[T] {
+	config := loaderConfig{wait: DefaultLoaderWait, maxBatch: DefaultLoaderMaxBatch}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return &Loader[T]{
+		call:     call,
+		build:    build,
+		id:       id,
+		wait:     config.wait,
+		maxBatch: config.maxBatch,
+		results:  map[int64]*loaderBatch[T]{},
+	}
+}
+
+func (l *Loader[T]) Load(ctx context.Context, id int64) (*T, error) {
+	batch := l.enqueue(id)
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	item, ok := batch.items[id]
+	if !ok {
+		return nil, &NotFoundError{ID: id}
+	}
+	return &item, nil
+}
+
+// LoadMany loads every id, returning the items in the same order. The first
+// error, including a NotFoundError, aborts the call.
+func (l *Loader[T]) LoadMany(ctx context.Context, ids []int64) ([]T, error) {
+	batches := make([]*loaderBatch[T], len(ids))
+	for i, id := range ids {
+		batches[i] = l.enqueue(id)
+	}
+
+	items := make([]T, 0, len(ids))
+	for i, id := range ids {
+		select {
+		case <-batches[i].done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if batches[i].err != nil {
+			return nil, batches[i].err
+		}
+		item, ok := batches[i].items[id]
+		if !ok {
+			return nil, &NotFoundError{ID: id}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (l *Loader[T]) enqueue(id int64) *loaderBatch[T] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if batch, ok := l.results[id]; ok {
+		return batch
+	}
+
+	if l.batch == nil {
+		l.batch = &loaderBatch[T]{done: make(chan struct{})}
+		batch := l.batch
+		time.AfterFunc(l.wait, func() { l.dispatch(batch) })
+	}
+	batch := l.batch
+	batch.ids = append(batch.ids, id)
+	l.results[id] = batch
+
+	if l.maxBatch > 0 && len(batch.ids) >= l.maxBatch {
+		l.batch = nil
+		go l.fetch(batch)
+	}
+	return batch
+}
+
+func (l *Loader[T]) dispatch(batch *loaderBatch[T]) {
+	l.mu.Lock()
+	if l.batch != batch {
+		// already dispatched because it reached the max size
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+
+	l.fetch(batch)
+}
+
+func (l *Loader[T]) fetch(batch *loaderBatch[T]) {
+	defer close(batch.done)
+	// a panicking List must not leave the waiters blocked forever
+	defer func() {
+		if r := recover(); r != nil {
+			batch.items = nil
+			batch.err = fmt.Errorf("loader list panicked: %v", r)
+			l.forget(batch)
+		}
+	}()
+
+	var items []T
+	if err := l.call.List(l.build(batch.ids), &items); err != nil {
+		batch.err = err
+		l.forget(batch)
+		return
+	}
+
+	batch.items = make(map[int64]T, len(items))
+	for _, item := range items {
+		batch.items[l.id(item)] = item
+	}
+}
+
+// forget drops a failed batch so that the ids can be retried.
+func (l *Loader[T]) forget(batch *loaderBatch[T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, id := range batch.ids {
+		if l.results[id] == batch {
+			delete(l.results, id)
+		}
+	}
+}
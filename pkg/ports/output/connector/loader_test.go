@@ -0,0 +1,221 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	loaded struct {
+		ID int64
+	}
+
+	// listCall answers List with one item per requested id, except the ids in
+	// missing, and records the ids of every batch. fail is called first and
+	// may return an error or panic.
+	listCall struct {
+		Call[loaded]
+		mu      sync.Mutex
+		batches [][]int64
+		missing map[int64]bool
+		fail    func(call int) error
+	}
+)
+
+func (c *listCall) List(parameter Parameter, response *[]loaded) error {
+	ids := parameter.Body.([]int64)
+	c.mu.Lock()
+	c.batches = append(c.batches, slices.Clone(ids))
+	call := len(c.batches)
+	c.mu.Unlock()
+
+	if c.fail != nil {
+		if err := c.fail(call); err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		if !c.missing[id] {
+			*response = append(*response, loaded{ID: id})
+		}
+	}
+	return nil
+}
+
+func (c *listCall) recorded() [][]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.batches)
+}
+
+func newTestLoader(call *listCall, opts ...LoaderOption) *Loader[loaded] {
+	return NewLoader[loaded](call, func(ids []int64) Parameter {
+		return Parameter{Body: ids}
+	}, func(item loaded) int64 {
+		return item.ID
+	}, opts...)
+}
+
+// loadAll loads ids concurrently and returns the errors by id.
+func loadAll(loader *Loader[loaded], ids ...int64) []error {
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			item, err := loader.Load(context.Background(), id)
+			if err == nil && item.ID != id {
+				err = errors.New("wrong item")
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestLoaderBatchesTheWindow(t *testing.T) {
+	call := &listCall{}
+	loader := newTestLoader(call, WithLoaderWait(20*time.Millisecond))
+
+	for _, err := range loadAll(loader, 1, 2, 3) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	batches := call.recorded()
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("batches = %v, want one of 3 ids", batches)
+	}
+}
+
+func TestLoaderFlushesFullBatches(t *testing.T) {
+	call := &listCall{}
+	// the window never elapses during the test
+	loader := newTestLoader(call, WithLoaderWait(time.Hour), WithLoaderMaxBatch(2))
+
+	for _, err := range loadAll(loader, 1, 2, 3, 4) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	batches := call.recorded()
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 2 {
+		t.Fatalf("batches = %v, want two of 2 ids", batches)
+	}
+}
+
+func TestLoaderRequestsDuplicatesOnce(t *testing.T) {
+	call := &listCall{}
+	loader := newTestLoader(call, WithLoaderWait(20*time.Millisecond))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		items, err := loader.LoadMany(context.Background(), []int64{1, 1, 2})
+		if err != nil || len(items) != 3 || items[0].ID != 1 || items[1].ID != 1 || items[2].ID != 2 {
+			t.Errorf("LoadMany = %v, %v", items, err)
+		}
+	}()
+	for _, err := range loadAll(loader, 1, 1, 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	batches := call.recorded()
+	if len(batches) != 1 || !slices.Equal(sorted(batches[0]), []int64{1, 2}) {
+		t.Fatalf("batches = %v, want one with ids 1 and 2", batches)
+	}
+
+	// results are memoized
+	loadAll(loader, 1, 2)
+	if n := len(call.recorded()); n != 1 {
+		t.Fatalf("%d batches after loading known ids, want 1", n)
+	}
+}
+
+func TestLoaderReportsMissingIds(t *testing.T) {
+	call := &listCall{missing: map[int64]bool{2: true}}
+	loader := newTestLoader(call)
+
+	errs := loadAll(loader, 1, 2)
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	var notFound *NotFoundError
+	if !errors.Is(errs[1], ErrNotFound) || !errors.As(errs[1], &notFound) || notFound.ID != 2 {
+		t.Fatalf("error = %v, want id 2 not found", errs[1])
+	}
+}
+
+func TestLoaderForgetsFailedBatches(t *testing.T) {
+	tests := map[string]struct {
+		fail func(call int) error
+		want string
+	}{
+		"error": {
+			fail: func(call int) error {
+				if call == 1 {
+					return errors.New("backend down")
+				}
+				return nil
+			},
+			want: "backend down",
+		},
+		"panic": {
+			fail: func(call int) error {
+				if call == 1 {
+					panic("boom")
+				}
+				return nil
+			},
+			want: "loader list panicked: boom",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			call := &listCall{fail: tt.fail}
+			loader := newTestLoader(call)
+
+			for _, err := range loadAll(loader, 1, 2) {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("error = %v, want %q", err, tt.want)
+				}
+			}
+			for _, err := range loadAll(loader, 1, 2) {
+				if err != nil {
+					t.Fatalf("retry after a failed batch: %v", err)
+				}
+			}
+			if n := len(call.recorded()); n != 2 {
+				t.Fatalf("%d batches, want the failed one and its retry", n)
+			}
+		})
+	}
+}
+
+func TestLoaderHonoursTheContext(t *testing.T) {
+	call := &listCall{}
+	loader := newTestLoader(call, WithLoaderWait(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := loader.Load(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func sorted(ids []int64) []int64 {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return ids
+}
@@ -11,4 +11,11 @@ type (
 		Update(parameter Parameter, response *T) error
 		Inative(parameter Parameter, response *T) error
 	}
+
+	// CursorCall is implemented by the calls supporting cursor pagination.
+	// It is kept apart from Call so that existing implementations of Call do
+	// not have to change.
+	CursorCall[T any] interface {
+		Cursor(parameter Parameter, response *CursorResponse[T]) error
+	}
 )
@@ -0,0 +1,189 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+var (
+	ErrTooManyItems      = errors.New("too many items")
+	ErrCursorUnsupported = errors.New("call does not support cursor pagination")
+)
+
+type (
+	// PageFetcher returns the given page of a listing.
+	PageFetcher[T any] func(ctx context.Context, page int) (*ListResponse[T], error)
+
+	// CursorFetcher returns the page starting at cursor, the empty cursor
+	// being the first page.
+	CursorFetcher[T any] func(ctx context.Context, cursor string) (*CursorResponse[T], error)
+
+	PaginationOption func(*paginationConfig)
+
+	paginationConfig struct {
+		firstPage int
+		prefetch  bool
+	}
+
+	// chunk is a page of items regardless of the pagination style, next being
+	// the page number or cursor that follows it.
+	chunk[T any, K any] struct {
+		items []T
+		next  K
+		more  bool
+		err   error
+	}
+)
+
+// WithFirstPage sets the number of the first page, 1 by default. It is
+// ignored by cursor pagination.
+func WithFirstPage(page int) PaginationOption {
+	return func(c *paginationConfig) {
+		c.firstPage = page
+	}
+}
+
+// WithPrefetch fetches the next page concurrently while the current one is
+// being consumed.
+func WithPrefetch() PaginationOption {
+	return func(c *paginationConfig) {
+		c.prefetch = true
+	}
+}
+
+// PagesFromCall adapts Call.Page to a PageFetcher. build returns the
+// parameter for a page, usually through rest.FindAll or a page query.
+func PagesFromCall[T any](call Call[T], build func(page int) Parameter) PageFetcher[T] {
+	return func(_ context.Context, page int) (*ListResponse[T], error) {
+		var response ListResponse[T]
+		if err := call.Page(build(page), &response); err != nil {
+			return nil, err
+		}
+		return &response, nil
+	}
+}
+
+// CursorsFromCall adapts the Cursor method of call to a CursorFetcher. build
+// returns the parameter for a cursor, see ParameterBuilder.WithCursor. The
+// fetcher fails with ErrCursorUnsupported when call is not a CursorCall.
+func CursorsFromCall[T any](call Call[T], build func(cursor string) Parameter) CursorFetcher[T] {
+	cursorCall, ok := call.(CursorCall[T])
+	return func(_ context.Context, cursor string) (*CursorResponse[T], error) {
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrCursorUnsupported, call)
+		}
+		var response CursorResponse[T]
+		if err := cursorCall.Cursor(build(cursor), &response); err != nil {
+			return nil, err
+		}
+		return &response, nil
+	}
+}
+
+// Paginate iterates over every item of every page, advancing the page until
+// HasNextPage is false. An error is yielded once and ends the iteration.
+func Paginate[T any](ctx context.Context, fetch PageFetcher[T], opts ...PaginationOption) iter.Seq2[T, error] {
+	config := newPaginationConfig(opts)
+	return walk(ctx, config.firstPage, config.prefetch, func(ctx context.Context, page int) chunk[T, int] {
+		response, err := fetch(ctx, page)
+		if err != nil {
+			return chunk[T, int]{err: fmt.Errorf("fetching page %d: %w", page, err)}
+		}
+		if response == nil {
+			return chunk[T, int]{}
+		}
+		return chunk[T, int]{
+			items: response.Data,
+			next:  page + 1,
+			more:  response.HasNextPage && len(response.Data) > 0,
+		}
+	})
+}
+
+// PaginateCursor iterates over every item, following NextCursor until it is
+// empty. An error is yielded once and ends the iteration.
+func PaginateCursor[T any](ctx context.Context, fetch CursorFetcher[T], opts ...PaginationOption) iter.Seq2[T, error] {
+	config := newPaginationConfig(opts)
+	return walk(ctx, "", config.prefetch, func(ctx context.Context, cursor string) chunk[T, string] {
+		response, err := fetch(ctx, cursor)
+		if err != nil {
+			return chunk[T, string]{err: fmt.Errorf("fetching cursor %q: %w", cursor, err)}
+		}
+		if response == nil {
+			return chunk[T, string]{}
+		}
+		return chunk[T, string]{
+			items: response.Data,
+			next:  response.NextCursor,
+			more:  response.NextCursor != "" && response.NextCursor != cursor,
+		}
+	})
+}
+
+// CollectAll drains seq into a slice. It fails with ErrTooManyItems once more
+// than maxItems items are produced; zero or a negative maxItems means no limit.
+func CollectAll[T any](seq iter.Seq2[T, error], maxItems int) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		if maxItems > 0 && len(items) >= maxItems {
+			return items, fmt.Errorf("%w: more than %d", ErrTooManyItems, maxItems)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func newPaginationConfig(opts []PaginationOption) paginationConfig {
+	config := paginationConfig{firstPage: 1}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+func walk[T any, K any](ctx context.Context, first K, prefetch bool, fetch func(context.Context, K) chunk[T, K]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var zero T
+		next := fetchAsync(ctx, fetch, first)
+		for {
+			current := <-next
+			if current.err != nil {
+				yield(zero, current.err)
+				return
+			}
+
+			if current.more && prefetch {
+				next = fetchAsync(ctx, fetch, current.next)
+			}
+
+			for _, item := range current.items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if !current.more {
+				return
+			}
+			if !prefetch {
+				next = fetchAsync(ctx, fetch, current.next)
+			}
+		}
+	}
+}
+
+func fetchAsync[T any, K any](ctx context.Context, fetch func(context.Context, K) chunk[T, K], key K) <-chan chunk[T, K] {
+	result := make(chan chunk[T, K], 1)
+	go func() {
+		result <- fetch(ctx, key)
+	}()
+	return result
+}
@@ -3,8 +3,11 @@ package connector
 import (
 	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"github.com/tecmise/connector-lib/pkg/ports/output/constant"
+	"github.com/tecmise/connector-lib/pkg/ports/output/credentials"
+	"github.com/tecmise/connector-lib/pkg/ports/output/idempotency"
+	"github.com/tecmise/connector-lib/pkg/ports/output/identity"
+	"github.com/tecmise/connector-lib/pkg/ports/output/logging"
 
 	"github.com/gofrs/uuid"
 )
@@ -17,7 +20,14 @@ type Parameter struct {
 	Region     constant.AWSRegion
 	UserID     uuid.UUID
 	UserPoolID string
+	Identity   identity.Identity
 	Headers    map[string]string
+	// ResourceTemplate, PathParameters and StageVariables complete the proxy
+	// event of Lambda-proxy calls, for functions routing on the API Gateway
+	// resource.
+	ResourceTemplate string
+	PathParameters   map[string]string
+	StageVariables   map[string]string
 }
 
 type ParameterBuilder struct {
@@ -28,6 +38,17 @@ func (b *Parameter) GetHttpURL() string {
 	return fmt.Sprintf("%s/%s", b.Host, b.Resource)
 }
 
+// ResolveIdentity returns the caller identity of the call: the Identity
+// field, completed by the legacy UserID and UserPoolID fields and by the
+// identity found in ctx.
+func (b *Parameter) ResolveIdentity(ctx context.Context) identity.Identity {
+	resolved := b.Identity.Merge(identity.Identity{UserID: b.UserID, UserPoolID: b.UserPoolID})
+	if fromContext, ok := identity.FromContext(ctx); ok {
+		resolved = resolved.Merge(fromContext)
+	}
+	return resolved
+}
+
 func NewParameterBuilder() *ParameterBuilder {
 	return &ParameterBuilder{param: Parameter{}}
 }
@@ -57,6 +78,11 @@ func (b *ParameterBuilder) WithRegion(region constant.AWSRegion) *ParameterBuild
 	return b
 }
 
+func (b *ParameterBuilder) WithIdentity(identity identity.Identity) *ParameterBuilder {
+	b.param.Identity = identity
+	return b
+}
+
 func (b *ParameterBuilder) WithHeader(key, value string) *ParameterBuilder {
 	if b.param.Headers == nil {
 		b.param.Headers = make(map[string]string)
@@ -65,20 +91,46 @@ func (b *ParameterBuilder) WithHeader(key, value string) *ParameterBuilder {
 	return b
 }
 
+// WithResourceTemplate sets the route template of a Lambda-proxy call, e.g.
+// /users/{id}, and the values of its path parameters.
+func (b *ParameterBuilder) WithResourceTemplate(template string, params map[string]string) *ParameterBuilder {
+	b.param.ResourceTemplate = template
+	b.param.PathParameters = params
+	return b
+}
+
+func (b *ParameterBuilder) WithStageVariables(variables map[string]string) *ParameterBuilder {
+	b.param.StageVariables = variables
+	return b
+}
+
+// WithIdempotencyKey sends key with the call so that its retries, and the
+// ones of the caller, are applied once.
+func (b *ParameterBuilder) WithIdempotencyKey(key string) *ParameterBuilder {
+	return b.WithHeader(idempotency.Header, key)
+}
+
+// WithCursor adds the cursor to the resource or headers as dictated by
+// strategy. It must be called after WithResource.
+func (b *ParameterBuilder) WithCursor(strategy CursorStrategy, cursor string) *ParameterBuilder {
+	b.param.Resource, b.param.Headers = strategy.Apply(b.param.Resource, b.param.Headers, cursor)
+	return b
+}
+
 func (b *ParameterBuilder) WithCredentials(ctx context.Context) *ParameterBuilder {
-	token := ctx.Value("bearer-token")
-	xApiKey := ctx.Value("x-api-key")
-	if token == nil {
-		logrus.Warnf("Token is null in context!")
+	token, hasToken := credentials.BearerToken(ctx)
+	xApiKey, hasApiKey := credentials.APIKey(ctx)
+	if !hasToken {
+		logging.Default().WarnContext(ctx, "bearer token missing from context")
 	}
-	if xApiKey == nil {
-		logrus.Warnf("X api key is null!")
+	if !hasApiKey {
+		logging.Default().WarnContext(ctx, "api key missing from context")
 	}
-	if token != nil {
-		b.WithHeader("Authorization", fmt.Sprintf("Bearer %s", token.(string)))
+	if hasToken {
+		b.WithHeader("Authorization", fmt.Sprintf("Bearer %s", token))
 	}
-	if xApiKey != nil {
-		b.WithHeader("x-api-key", xApiKey.(string))
+	if hasApiKey {
+		b.WithHeader("x-api-key", xApiKey)
 	}
 	return b
 }
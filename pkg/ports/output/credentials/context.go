@@ -0,0 +1,44 @@
+package credentials
+
+import "context"
+
+const (
+	// legacy untyped keys, still read for services that did not migrate
+	legacyBearerTokenKey = "bearer-token"
+	legacyAPIKeyKey      = "x-api-key"
+)
+
+type (
+	bearerTokenKey struct{}
+	apiKeyKey      struct{}
+)
+
+func WithBearerToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, bearerTokenKey{}, token)
+}
+
+func WithAPIKey(ctx context.Context, apiKey string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, apiKey)
+}
+
+// BearerToken returns the token stored with WithBearerToken, falling back to
+// the legacy "bearer-token" key. Values that are not strings are ignored.
+func BearerToken(ctx context.Context) (string, bool) {
+	return lookup(ctx, bearerTokenKey{}, legacyBearerTokenKey)
+}
+
+// APIKey returns the key stored with WithAPIKey, falling back to the legacy
+// "x-api-key" key. Values that are not strings are ignored.
+func APIKey(ctx context.Context) (string, bool) {
+	return lookup(ctx, apiKeyKey{}, legacyAPIKeyKey)
+}
+
+func lookup(ctx context.Context, key interface{}, legacyKey string) (string, bool) {
+	if value, ok := ctx.Value(key).(string); ok && value != "" {
+		return value, true
+	}
+	if value, ok := ctx.Value(legacyKey).(string); ok && value != "" {
+		return value, true
+	}
+	return "", false
+}
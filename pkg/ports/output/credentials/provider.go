@@ -0,0 +1,138 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type (
+	Credentials struct {
+		BearerToken string
+		APIKey      string
+	}
+
+	// Provider resolves the credentials of an outbound call. It is invoked
+	// for every call so implementations may refresh or rotate them.
+	Provider interface {
+		Credentials(ctx context.Context) (Credentials, error)
+	}
+
+	ProviderFunc func(ctx context.Context) (Credentials, error)
+
+	// TokenSource supplies bearer tokens, caching and renewing them as needed.
+	TokenSource interface {
+		AccessToken(ctx context.Context) (string, error)
+	}
+
+	static struct {
+		credentials Credentials
+	}
+
+	fromContext struct{}
+
+	apiKeyFromEnv struct {
+		variable string
+	}
+
+	chain struct {
+		providers []Provider
+	}
+)
+
+func (f ProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// Headers renders the credentials as the Authorization and x-api-key headers.
+func (c Credentials) Headers() map[string]string {
+	headers := map[string]string{}
+	if c.BearerToken != "" {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", c.BearerToken)
+	}
+	if c.APIKey != "" {
+		headers["x-api-key"] = c.APIKey
+	}
+	return headers
+}
+
+func (c Credentials) IsEmpty() bool {
+	return c.BearerToken == "" && c.APIKey == ""
+}
+
+func Static(credentials Credentials) Provider {
+	return static{credentials: credentials}
+}
+
+func (s static) Credentials(context.Context) (Credentials, error) {
+	return s.credentials, nil
+}
+
+// FromContext forwards the credentials of the caller found in ctx. It is the
+// default provider, matching the historical behaviour of the clients.
+func FromContext() Provider {
+	return fromContext{}
+}
+
+func (fromContext) Credentials(ctx context.Context) (Credentials, error) {
+	token, _ := BearerToken(ctx)
+	apiKey, _ := APIKey(ctx)
+	return Credentials{BearerToken: token, APIKey: apiKey}, nil
+}
+
+// APIKeyFromEnv reads the API key from the environment variable on each
+// call, so a rotated value is picked up without restarting.
+func APIKeyFromEnv(variable string) Provider {
+	return apiKeyFromEnv{variable: variable}
+}
+
+func (a apiKeyFromEnv) Credentials(context.Context) (Credentials, error) {
+	apiKey, ok := os.LookupEnv(a.variable)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s not set", a.variable)
+	}
+	return Credentials{APIKey: apiKey}, nil
+}
+
+// Chain merges the credentials of providers, the first non empty value of
+// each field winning. Errors are returned only when no provider succeeded.
+func Chain(providers ...Provider) Provider {
+	return chain{providers: providers}
+}
+
+func (c chain) Credentials(ctx context.Context) (Credentials, error) {
+	var result Credentials
+	var errs []error
+	succeeded := false
+	for _, provider := range c.providers {
+		credentials, err := provider.Credentials(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		succeeded = true
+		if result.BearerToken == "" {
+			result.BearerToken = credentials.BearerToken
+		}
+		if result.APIKey == "" {
+			result.APIKey = credentials.APIKey
+		}
+	}
+	if !succeeded && len(errs) > 0 {
+		return Credentials{}, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// FromTokenSource uses the tokens of source as bearer token, e.g. machine
+// tokens issued by an OAuth2 client credentials grant.
+func FromTokenSource(source TokenSource) Provider {
+	return ProviderFunc(func(ctx context.Context) (Credentials, error) {
+		token, err := source.AccessToken(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		return Credentials{BearerToken: token}, nil
+	})
+}
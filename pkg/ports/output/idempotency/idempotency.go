@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+const Header = "Idempotency-Key"
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the idempotency key of a logical operation,
+// so that every call made for it, including the retries of the caller, is
+// sent with the same key.
+func NewContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(contextKey{}).(string)
+	return key, ok && key != ""
+}
+
+func NewKey() string {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+// Mutating reports whether method can not be repeated without a key since
+// the backend would apply it twice.
+func Mutating(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// Apply returns a copy of headers with key as Idempotency-Key, unless key is
+// empty or the caller set one explicitly.
+func Apply(headers map[string]string, key string) map[string]string {
+	if key == "" || Of(headers) != "" {
+		return headers
+	}
+	result := make(map[string]string, len(headers)+1)
+	for name, value := range headers {
+		result[name] = value
+	}
+	result[Header] = key
+	return result
+}
+
+// Of returns the Idempotency-Key of headers, whatever its case.
+func Of(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, Header) {
+			return value
+		}
+	}
+	return ""
+}
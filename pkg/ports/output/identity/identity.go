@@ -0,0 +1,149 @@
+package identity
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	HeaderUserID        = "X-authenticated-user"
+	HeaderUserPool      = "X-user-pool"
+	HeaderTenant        = "X-tenant-id"
+	HeaderRoles         = "X-user-roles"
+	HeaderCorrelationID = "X-correlation-id"
+)
+
+type (
+	// Identity describes the caller on whose behalf an outbound call is made.
+	// Every transport serializes it the same way so downstream services see
+	// the same values however they are invoked.
+	Identity struct {
+		UserID        uuid.UUID
+		UserPoolID    string
+		TenantID      string
+		Roles         []string
+		CorrelationID string
+	}
+
+	contextKey struct{}
+)
+
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
+
+// Merge returns i completed with the fields of other that i does not set.
+func (i Identity) Merge(other Identity) Identity {
+	if i.UserID == uuid.Nil {
+		i.UserID = other.UserID
+	}
+	if i.UserPoolID == "" {
+		i.UserPoolID = other.UserPoolID
+	}
+	if i.TenantID == "" {
+		i.TenantID = other.TenantID
+	}
+	if len(i.Roles) == 0 {
+		i.Roles = other.Roles
+	}
+	if i.CorrelationID == "" {
+		i.CorrelationID = other.CorrelationID
+	}
+	return i
+}
+
+// WithCorrelationID returns i with a generated correlation id when it has none.
+func (i Identity) WithCorrelationID() Identity {
+	if i.CorrelationID == "" {
+		if id, err := uuid.NewV4(); err == nil {
+			i.CorrelationID = id.String()
+		}
+	}
+	return i
+}
+
+// Headers renders the identity as request headers, omitting unset fields.
+func (i Identity) Headers() map[string]string {
+	headers := map[string]string{}
+	if i.UserID != uuid.Nil {
+		headers[HeaderUserID] = i.UserID.String()
+	}
+	if i.UserPoolID != "" {
+		headers[HeaderUserPool] = i.UserPoolID
+	}
+	if i.TenantID != "" {
+		headers[HeaderTenant] = i.TenantID
+	}
+	if len(i.Roles) > 0 {
+		headers[HeaderRoles] = strings.Join(i.Roles, ",")
+	}
+	if i.CorrelationID != "" {
+		headers[HeaderCorrelationID] = i.CorrelationID
+	}
+	return headers
+}
+
+// Apply returns a copy of headers with the identity headers the caller did
+// not set explicitly.
+func (i Identity) Apply(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers)+5)
+	for key, value := range headers {
+		result[key] = value
+	}
+	for key, value := range i.Headers() {
+		if !contains(result, key) {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// Authorizer renders the identity as the requestContext.authorizer of an API
+// Gateway proxy event, in the shape of a Lambda authorizer context plus
+// Cognito-like claims.
+func (i Identity) Authorizer() map[string]interface{} {
+	authorizer := map[string]interface{}{}
+	claims := map[string]interface{}{}
+	if i.UserID != uuid.Nil {
+		authorizer["principalId"] = i.UserID.String()
+		authorizer["userId"] = i.UserID.String()
+		claims["sub"] = i.UserID.String()
+	}
+	if i.UserPoolID != "" {
+		authorizer["userPoolId"] = i.UserPoolID
+	}
+	if i.TenantID != "" {
+		authorizer["tenantId"] = i.TenantID
+		claims["custom:tenant_id"] = i.TenantID
+	}
+	if len(i.Roles) > 0 {
+		authorizer["roles"] = strings.Join(i.Roles, ",")
+		claims["cognito:groups"] = strings.Join(i.Roles, ",")
+	}
+	if i.CorrelationID != "" {
+		authorizer["correlationId"] = i.CorrelationID
+	}
+	if len(claims) > 0 {
+		authorizer["claims"] = claims
+	}
+	if len(authorizer) == 0 {
+		return nil
+	}
+	return authorizer
+}
+
+func contains(headers map[string]string, name string) bool {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) && value != "" {
+			return true
+		}
+	}
+	return false
+}
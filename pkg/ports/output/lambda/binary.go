@@ -0,0 +1,20 @@
+package lambda
+
+import (
+	"bytes"
+	"io"
+)
+
+type (
+	// Binary is a raw body, sent base64 encoded with isBase64Encoded through
+	// the proxy event. It can be used as request body and as response type
+	// for downloads such as PDFs or images.
+	Binary struct {
+		ContentType string
+		Data        []byte
+	}
+)
+
+func (b Binary) Reader() io.Reader {
+	return bytes.NewReader(b.Data)
+}
@@ -0,0 +1,72 @@
+package lambda
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	// FunctionError is the payload of an invocation that failed inside the
+	// function. Kind is the X-Amz-Function-Error value, Handled or Unhandled,
+	// and LogTail the end of the execution log when it was requested.
+	FunctionError struct {
+		Kind         string   `json:"-"`
+		RequestID    string   `json:"-"`
+		LogTail      string   `json:"-"`
+		ErrorMessage string   `json:"errorMessage"`
+		ErrorType    string   `json:"errorType"`
+		StackTrace   []string `json:"stackTrace,omitempty"`
+	}
+
+	// StatusError is returned for a proxy response with a non-2xx statusCode.
+	StatusError struct {
+		StatusCode int
+		Headers    map[string]string
+		Body       []byte
+		Message    string
+	}
+)
+
+// NewFunctionError decodes the error payload of an invocation, keeping the
+// raw payload as message when it is not the Lambda error document.
+func NewFunctionError(kind string, payload []byte) *FunctionError {
+	functionError := &FunctionError{Kind: kind}
+	if err := json.Unmarshal(payload, functionError); err != nil || functionError.ErrorMessage == "" {
+		functionError.ErrorMessage = strings.TrimSpace(string(payload))
+	}
+	return functionError
+}
+
+func (e *FunctionError) Error() string {
+	if e.ErrorType == "" {
+		return fmt.Sprintf("lambda %s error: %s", strings.ToLower(e.Kind), e.ErrorMessage)
+	}
+	return fmt.Sprintf("lambda %s error: %s: %s", strings.ToLower(e.Kind), e.ErrorType, e.ErrorMessage)
+}
+
+// NewStatusError builds the error of response, taking its message from the
+// {"content": ...} document the services return when there is one.
+func NewStatusError(response *Response) *StatusError {
+	body, err := response.BodyBytes()
+	if err != nil {
+		body = []byte(response.Body)
+	}
+	statusError := &StatusError{
+		StatusCode: response.StatusCode,
+		Headers:    response.Headers,
+		Body:       body,
+		Message:    strings.TrimSpace(string(body)),
+	}
+	var content struct {
+		Content string `json:"content"`
+	}
+	if json.Unmarshal(body, &content) == nil && content.Content != "" {
+		statusError.Message = content.Content
+	}
+	return statusError
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("lambda proxy returned status %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,235 @@
+package lambda
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	PayloadVersion1 PayloadVersion = "1.0"
+	PayloadVersion2 PayloadVersion = "2.0"
+
+	DefaultStage    = "$default"
+	defaultSourceIP = "127.0.0.1"
+	defaultProtocol = "HTTP/1.1"
+)
+
+type (
+	// PayloadVersion selects the API Gateway proxy event format emulated when
+	// invoking a function: 1.0 for REST APIs, 2.0 for HTTP APIs.
+	PayloadVersion string
+
+	// Event describes an HTTP request independently of the payload format.
+	// Build renders it as the event API Gateway would send to the function,
+	// so routers such as aws-lambda-go-api-proxy behave as behind a gateway.
+	Event struct {
+		Method string
+		// Resource is the route template, e.g. /users/{id}. Path is used
+		// when empty.
+		Resource        string
+		Path            string
+		Query           url.Values
+		PathParameters  map[string]string
+		Headers         map[string]string
+		Body            string
+		IsBase64Encoded bool
+		Stage           string
+		StageVariables  map[string]string
+		Authorizer      map[string]interface{}
+	}
+)
+
+// NewEvent returns an event for resource, a path optionally followed by a
+// query string.
+func NewEvent(method, resource string) Event {
+	path, rawQuery, _ := strings.Cut(resource, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		query = url.Values{}
+	}
+	return Event{
+		Method:  strings.ToUpper(method),
+		Path:    path,
+		Query:   query,
+		Headers: map[string]string{},
+	}
+}
+
+// SetResource sets the route template of the event, e.g. /users/{id}, and
+// the values of its path parameters. When the path is the template itself it
+// is rendered from params.
+func (e *Event) SetResource(template string, params map[string]string) {
+	e.Resource = template
+	e.PathParameters = params
+	if e.Path != "" && strings.Trim(e.Path, "/") != strings.Trim(template, "/") {
+		return
+	}
+	path := template
+	for name, value := range params {
+		path = strings.ReplaceAll(path, "{"+name+"+}", value)
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	e.Path = path
+}
+
+func (e Event) Build(version PayloadVersion) interface{} {
+	if version == PayloadVersion2 {
+		return e.BuildV2()
+	}
+	return e.BuildV1()
+}
+
+func (e Event) BuildV1() Payload {
+	now := time.Now()
+	resource := e.resource()
+
+	var query map[string]string
+	var multiValueQuery map[string][]string
+	if len(e.Query) > 0 {
+		query = make(map[string]string, len(e.Query))
+		multiValueQuery = make(map[string][]string, len(e.Query))
+		for key, values := range e.Query {
+			query[key] = values[len(values)-1]
+			multiValueQuery[key] = values
+		}
+	}
+
+	multiValueHeaders := make(map[string][]string, len(e.Headers))
+	for key, value := range e.Headers {
+		multiValueHeaders[key] = []string{value}
+	}
+
+	payload := Payload{
+		Resource:                        resource,
+		Path:                            e.Path,
+		HttpMethod:                      e.Method,
+		Headers:                         e.Headers,
+		MultiValueHeaders:               multiValueHeaders,
+		QueryStringParameters:           query,
+		MultiValueQueryStringParameters: multiValueQuery,
+		StageVariables:                  e.StageVariables,
+		RequestContext: RequestContext{
+			Stage:            e.stage(),
+			RequestID:        requestID(),
+			Protocol:         defaultProtocol,
+			RequestTime:      now.UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			RequestTimeEpoch: now.UnixMilli(),
+			Identity: RequestIdentity{
+				SourceIP:  defaultSourceIP,
+				UserAgent: header(e.Headers, "User-Agent"),
+			},
+			ResourcePath: resource,
+			Path:         e.Path,
+			HttpMethod:   e.Method,
+			Authorizer:   e.Authorizer,
+		},
+		Body:            e.Body,
+		IsBase64Encoded: e.IsBase64Encoded,
+	}
+	if len(e.PathParameters) > 0 {
+		payload.PathParameters = e.PathParameters
+	}
+	return payload
+}
+
+func (e Event) BuildV2() PayloadV2 {
+	now := time.Now()
+	routeKey := e.Method + " " + e.resource()
+
+	// format 2.0 lower cases header names and carries cookies apart
+	headers := make(map[string]string, len(e.Headers))
+	var cookies []string
+	for key, value := range e.Headers {
+		name := strings.ToLower(key)
+		if name == "cookie" {
+			for _, cookie := range strings.Split(value, ";") {
+				if cookie = strings.TrimSpace(cookie); cookie != "" {
+					cookies = append(cookies, cookie)
+				}
+			}
+			continue
+		}
+		if existing, ok := headers[name]; ok {
+			value = existing + "," + value
+		}
+		headers[name] = value
+	}
+
+	var query map[string]string
+	if len(e.Query) > 0 {
+		query = make(map[string]string, len(e.Query))
+		for key, values := range e.Query {
+			query[key] = strings.Join(values, ",")
+		}
+	}
+
+	payload := PayloadV2{
+		Version:               string(PayloadVersion2),
+		RouteKey:              routeKey,
+		RawPath:               e.Path,
+		RawQueryString:        e.Query.Encode(),
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: query,
+		PathParameters:        e.PathParameters,
+		StageVariables:        e.StageVariables,
+		RequestContext: RequestContextV2{
+			HTTP: RequestContextV2HTTP{
+				Method:    e.Method,
+				Path:      e.Path,
+				Protocol:  defaultProtocol,
+				SourceIP:  defaultSourceIP,
+				UserAgent: header(e.Headers, "User-Agent"),
+			},
+			RequestID: requestID(),
+			RouteKey:  routeKey,
+			Stage:     e.stage(),
+			Time:      now.UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch: now.UnixMilli(),
+		},
+		Body:            e.Body,
+		IsBase64Encoded: e.IsBase64Encoded,
+	}
+	if len(e.Authorizer) > 0 {
+		authorizer := &AuthorizerV2{Lambda: e.Authorizer}
+		if claims, ok := e.Authorizer["claims"].(map[string]interface{}); ok {
+			authorizer.JWT = &AuthorizerV2JWT{Claims: claims}
+		}
+		payload.RequestContext.Authorizer = authorizer
+	}
+	return payload
+}
+
+func (e Event) resource() string {
+	if e.Resource != "" {
+		return e.Resource
+	}
+	return e.Path
+}
+
+func (e Event) stage() string {
+	if e.Stage != "" {
+		return e.Stage
+	}
+	return DefaultStage
+}
+
+func requestID() string {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+func header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package lambda
 
 type (
+	// Payload is the API Gateway REST API (payload format 1.0) proxy event.
 	Payload struct {
 		Resource                        string              `json:"resource"`
 		Path                            string              `json:"path"`
@@ -10,7 +11,25 @@ type (
 		QueryStringParameters           interface{}         `json:"queryStringParameters"`
 		MultiValueQueryStringParameters interface{}         `json:"multiValueQueryStringParameters"`
 		PathParameters                  interface{}         `json:"pathParameters"`
+		StageVariables                  map[string]string   `json:"stageVariables"`
 		RequestContext                  RequestContext      `json:"requestContext"`
 		Body                            string              `json:"body"`
+		IsBase64Encoded                 bool                `json:"isBase64Encoded"`
+	}
+
+	// PayloadV2 is the API Gateway HTTP API (payload format 2.0) proxy event.
+	PayloadV2 struct {
+		Version               string            `json:"version"`
+		RouteKey              string            `json:"routeKey"`
+		RawPath               string            `json:"rawPath"`
+		RawQueryString        string            `json:"rawQueryString"`
+		Cookies               []string          `json:"cookies,omitempty"`
+		Headers               map[string]string `json:"headers"`
+		QueryStringParameters map[string]string `json:"queryStringParameters,omitempty"`
+		PathParameters        map[string]string `json:"pathParameters,omitempty"`
+		StageVariables        map[string]string `json:"stageVariables,omitempty"`
+		RequestContext        RequestContextV2  `json:"requestContext"`
+		Body                  string            `json:"body,omitempty"`
+		IsBase64Encoded       bool              `json:"isBase64Encoded"`
 	}
 )
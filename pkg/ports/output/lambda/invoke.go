@@ -0,0 +1,190 @@
+package lambda
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type (
+	InvokeOption func(*InvokeConfig)
+
+	// InvokeConfig holds the per-call settings of an invocation.
+	InvokeConfig struct {
+		InvocationType types.InvocationType
+		Qualifier      string
+		ClientContext  *ClientContext
+		LogTail        bool
+		// Resource, PathParameters and StageVariables complete the proxy event
+		// for functions routing on the API Gateway resource.
+		Resource       string
+		PathParameters map[string]string
+		StageVariables map[string]string
+		invocation     *Invocation
+	}
+
+	// ClientContext is the context passed to the function, available to it as
+	// context.clientContext.
+	ClientContext struct {
+		Client map[string]string `json:"client,omitempty"`
+		Custom map[string]string `json:"custom,omitempty"`
+		Env    map[string]string `json:"env,omitempty"`
+	}
+
+	// Invocation describes an invocation as acknowledged by Lambda. It is the
+	// only result of Event and DryRun invocations.
+	Invocation struct {
+		StatusCode      int32
+		RequestID       string
+		ExecutedVersion string
+		LogTail         string
+	}
+)
+
+// NewInvokeConfig applies opts over a RequestResponse invocation.
+func NewInvokeConfig(opts ...InvokeOption) InvokeConfig {
+	config := InvokeConfig{InvocationType: types.InvocationTypeRequestResponse}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// AsEvent invokes the function asynchronously, Lambda answering 202 as soon
+// as the event is queued.
+func AsEvent() InvokeOption {
+	return WithInvocationType(types.InvocationTypeEvent)
+}
+
+// AsDryRun only checks that the caller is allowed to invoke the function.
+func AsDryRun() InvokeOption {
+	return WithInvocationType(types.InvocationTypeDryRun)
+}
+
+func WithInvocationType(invocationType types.InvocationType) InvokeOption {
+	return func(c *InvokeConfig) {
+		c.InvocationType = invocationType
+	}
+}
+
+// WithQualifier invokes a version or alias of the function.
+func WithQualifier(qualifier string) InvokeOption {
+	return func(c *InvokeConfig) {
+		c.Qualifier = qualifier
+	}
+}
+
+func WithClientContext(clientContext ClientContext) InvokeOption {
+	return func(c *InvokeConfig) {
+		c.ClientContext = &clientContext
+	}
+}
+
+// WithLogTail asks Lambda for the last 4 KB of the execution log, attached to
+// the Invocation and to a FunctionError.
+func WithLogTail() InvokeOption {
+	return func(c *InvokeConfig) {
+		c.LogTail = true
+	}
+}
+
+// WithResource sends the route template of the call, e.g. /users/{id}, and
+// the values of its path parameters in the proxy event.
+func WithResource(template string, params map[string]string) InvokeOption {
+	return func(c *InvokeConfig) {
+		c.Resource = template
+		c.PathParameters = params
+	}
+}
+
+// WithStageVariables sends stage variables in the proxy event.
+func WithStageVariables(variables map[string]string) InvokeOption {
+	return func(c *InvokeConfig) {
+		c.StageVariables = variables
+	}
+}
+
+// WithInvocation stores the status code and request id of the invocation in
+// invocation once it is acknowledged.
+func WithInvocation(invocation *Invocation) InvokeOption {
+	return func(c *InvokeConfig) {
+		c.invocation = invocation
+	}
+}
+
+// ApplyEvent sets the resource, path parameters and stage variables of the
+// call on event.
+func (c InvokeConfig) ApplyEvent(event *Event) {
+	if c.Resource != "" {
+		event.SetResource(c.Resource, c.PathParameters)
+	}
+	if c.StageVariables != nil {
+		event.StageVariables = c.StageVariables
+	}
+}
+
+// IsSync reports whether the invocation returns the function's response.
+func (c InvokeConfig) IsSync() bool {
+	return c.InvocationType == "" || c.InvocationType == types.InvocationTypeRequestResponse
+}
+
+// Apply sets the invocation type, qualifier and client context on input.
+func (c InvokeConfig) Apply(input *lambda.InvokeInput) error {
+	input.InvocationType = c.InvocationType
+	if c.LogTail {
+		input.LogType = types.LogTypeTail
+	}
+	if c.Qualifier != "" {
+		input.Qualifier = aws.String(c.Qualifier)
+	}
+	if c.ClientContext != nil {
+		encoded, err := c.ClientContext.Encode()
+		if err != nil {
+			return err
+		}
+		input.ClientContext = aws.String(encoded)
+	}
+	return nil
+}
+
+// Acknowledge records the invocation of output, see WithInvocation.
+func (c InvokeConfig) Acknowledge(output *lambda.InvokeOutput) Invocation {
+	invocation := NewInvocation(output)
+	if c.invocation != nil {
+		*c.invocation = invocation
+	}
+	return invocation
+}
+
+// Encode returns the base64 JSON document expected by InvokeInput.
+func (c ClientContext) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func NewInvocation(output *lambda.InvokeOutput) Invocation {
+	invocation := Invocation{
+		StatusCode:      output.StatusCode,
+		ExecutedVersion: aws.ToString(output.ExecutedVersion),
+		LogTail:         DecodeLogResult(output.LogResult),
+	}
+	invocation.RequestID, _ = awsmiddleware.GetRequestIDMetadata(output.ResultMetadata)
+	return invocation
+}
+
+// DecodeLogResult decodes the base64 log tail of an invocation, returning an
+// empty string when there is none.
+func DecodeLogResult(logResult *string) string {
+	data, err := base64.StdEncoding.DecodeString(aws.ToString(logResult))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -1,39 +1,39 @@
 package lambda
 
 import (
-	"encoding/json"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/sirupsen/logrus"
+	"github.com/tecmise/connector-lib/pkg/ports/output/logging"
 )
 
+// Deprecated: InvokeOutputResult is the raw result of the former
+// LambdaProxyProtocolClient, which now returns decoded ProxyResult values.
 type InvokeOutputResult[R any] struct {
 	Output *lambda.InvokeOutput
 	Error  error
 }
 
 func (r InvokeOutputResult[R]) Marshal(response interface{}) error {
+	log := logging.Default()
 	if r.Error != nil {
-		logrus.Errorf(r.Error.Error())
+		log.Error("lambda invocation failed", "error", r.Error)
 		return r.Error
 	}
 
 	resp := r.Output
 	if resp.StatusCode == 204 {
-		logrus.Debugf("Lambda retornou status code 204 (No Content)")
 		return nil
 	}
 
-	var result Response
-	err := json.Unmarshal(resp.Payload, &result)
+	result, err := DecodeResponse(resp.Payload)
 	if err != nil {
-		logrus.Errorf("Erro ao desserializar o payload de resposta da Lambda: %v", err)
+		log.Error("failed to unmarshal lambda response", "error", err)
 		return err
 	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		err = json.Unmarshal([]byte(result.Body), &response)
+		err = result.Decode(response)
 		if err != nil {
-			logrus.Errorf("Erro ao desserializar a resposta de erro da Lambda: %v", err)
+			log.Error("failed to unmarshal lambda response body", "error", err)
 			return err
 		}
 	}
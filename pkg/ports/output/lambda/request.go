@@ -18,8 +18,56 @@ type (
 	}
 
 	RequestContext struct {
-		ResourcePath string `json:"resourcePath"`
-		Path         string `json:"path"`
-		HttpMethod   string `json:"httpMethod"`
+		AccountID        string                 `json:"accountId,omitempty"`
+		ApiID            string                 `json:"apiId,omitempty"`
+		ResourceID       string                 `json:"resourceId,omitempty"`
+		Stage            string                 `json:"stage,omitempty"`
+		RequestID        string                 `json:"requestId,omitempty"`
+		DomainName       string                 `json:"domainName,omitempty"`
+		Protocol         string                 `json:"protocol,omitempty"`
+		RequestTime      string                 `json:"requestTime,omitempty"`
+		RequestTimeEpoch int64                  `json:"requestTimeEpoch,omitempty"`
+		Identity         RequestIdentity        `json:"identity"`
+		ResourcePath     string                 `json:"resourcePath"`
+		Path             string                 `json:"path"`
+		HttpMethod       string                 `json:"httpMethod"`
+		Authorizer       map[string]interface{} `json:"authorizer,omitempty"`
+	}
+
+	RequestIdentity struct {
+		SourceIP  string `json:"sourceIp"`
+		UserAgent string `json:"userAgent"`
+	}
+
+	RequestContextV2 struct {
+		AccountID    string               `json:"accountId,omitempty"`
+		ApiID        string               `json:"apiId,omitempty"`
+		DomainName   string               `json:"domainName,omitempty"`
+		DomainPrefix string               `json:"domainPrefix,omitempty"`
+		HTTP         RequestContextV2HTTP `json:"http"`
+		RequestID    string               `json:"requestId,omitempty"`
+		RouteKey     string               `json:"routeKey"`
+		Stage        string               `json:"stage"`
+		Time         string               `json:"time,omitempty"`
+		TimeEpoch    int64                `json:"timeEpoch,omitempty"`
+		Authorizer   *AuthorizerV2        `json:"authorizer,omitempty"`
+	}
+
+	RequestContextV2HTTP struct {
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		Protocol  string `json:"protocol"`
+		SourceIP  string `json:"sourceIp"`
+		UserAgent string `json:"userAgent"`
+	}
+
+	AuthorizerV2 struct {
+		JWT    *AuthorizerV2JWT       `json:"jwt,omitempty"`
+		Lambda map[string]interface{} `json:"lambda,omitempty"`
+	}
+
+	AuthorizerV2JWT struct {
+		Claims map[string]interface{} `json:"claims"`
+		Scopes []string               `json:"scopes,omitempty"`
 	}
 )
@@ -1,12 +1,106 @@
 package lambda
 
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
 type (
+	// Response is a proxy integration response, in either payload format.
 	Response struct {
-		StatusCode        int         `json:"statusCode"`
-		Headers           interface{} `json:"headers"`
-		MultiValueHeaders struct {
-			ContentType []string `json:"Content-Type"`
-		} `json:"multiValueHeaders"`
-		Body string `json:"body"`
+		StatusCode        int                 `json:"statusCode"`
+		Headers           map[string]string   `json:"headers"`
+		MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+		Cookies           []string            `json:"cookies,omitempty"`
+		Body              string              `json:"body"`
+		IsBase64Encoded   bool                `json:"isBase64Encoded"`
 	}
+
+	// Decoder unmarshals a body according to its content type.
+	Decoder func(contentType string, data []byte, v interface{}) error
 )
+
+// DecodeResponse decodes a proxy response of either payload format. As API
+// Gateway does for format 2.0, a payload without statusCode is taken as a
+// 200 response whose body is the payload itself.
+func DecodeResponse(payload []byte) (*Response, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &probe); err == nil {
+		if _, ok := probe["statusCode"]; ok {
+			var response Response
+			if err := json.Unmarshal(payload, &response); err != nil {
+				return nil, err
+			}
+			return &response, nil
+		}
+	}
+
+	body := bytes.TrimSpace(payload)
+	var text string
+	if json.Unmarshal(body, &text) == nil {
+		body = []byte(text)
+	}
+	return &Response{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"content-type": "application/json"},
+		Body:       string(body),
+	}, nil
+}
+
+// Header returns the first value of the header name, whatever its case.
+func (r *Response) Header(name string) string {
+	for key, value := range r.Headers {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) {
+			return value
+		}
+	}
+	for key, values := range r.MultiValueHeaders {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// BodyBytes returns the body, base64 decoded when isBase64Encoded is set.
+func (r *Response) BodyBytes() ([]byte, error) {
+	if !r.IsBase64Encoded {
+		return []byte(r.Body), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding base64 body: %w", err)
+	}
+	return data, nil
+}
+
+// Decode stores the body in out: as is for *[]byte and *Binary, decoded
+// from JSON otherwise.
+func (r *Response) Decode(out interface{}) error {
+	return r.DecodeWith(out, nil)
+}
+
+// DecodeWith is Decode unmarshaling with decoder, JSON when it is nil.
+func (r *Response) DecodeWith(out interface{}, decoder Decoder) error {
+	data, err := r.BodyBytes()
+	if err != nil {
+		return err
+	}
+	switch v := out.(type) {
+	case *[]byte:
+		*v = data
+		return nil
+	case *Binary:
+		v.Data = data
+		v.ContentType = r.Header("Content-Type")
+		return nil
+	default:
+		if decoder == nil {
+			return json.Unmarshal(data, out)
+		}
+		return decoder(r.Header("Content-Type"), data, out)
+	}
+}
@@ -0,0 +1,51 @@
+package lambda
+
+import "net/http"
+
+type (
+	// ProxyResult is a decoded proxy response. Body is nil for 204 responses
+	// and for Event and DryRun invocations.
+	ProxyResult[R any] struct {
+		Invocation        Invocation
+		StatusCode        int
+		Headers           map[string]string
+		MultiValueHeaders map[string][]string
+		Cookies           []string
+		Body              *R
+	}
+)
+
+// DecodeResult decodes payload into a ProxyResult, turning a non-2xx
+// statusCode into a *StatusError. A nil decoder decodes the body as JSON.
+func DecodeResult[R any](payload []byte, decoder Decoder) (*ProxyResult[R], error) {
+	response, err := DecodeResponse(payload)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, NewStatusError(response)
+	}
+
+	result := &ProxyResult[R]{
+		StatusCode:        response.StatusCode,
+		Headers:           response.Headers,
+		MultiValueHeaders: response.MultiValueHeaders,
+		Cookies:           response.Cookies,
+	}
+	if response.StatusCode == http.StatusNoContent || (response.Body == "" && !response.IsBase64Encoded) {
+		return result, nil
+	}
+
+	var body R
+	if err := response.DecodeWith(&body, decoder); err != nil {
+		return nil, err
+	}
+	result.Body = &body
+	return result, nil
+}
+
+// Header returns the first value of the header name, whatever its case.
+func (r *ProxyResult[R]) Header(name string) string {
+	response := Response{Headers: r.Headers, MultiValueHeaders: r.MultiValueHeaders}
+	return response.Header(name)
+}
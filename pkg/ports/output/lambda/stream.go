@@ -0,0 +1,45 @@
+package lambda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+type (
+	// StreamError is reported by the completion event of a response stream
+	// that failed after it started.
+	StreamError struct {
+		ErrorCode    string
+		ErrorDetails string
+		LogResult    string
+	}
+)
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("lambda response stream failed: %s: %s", e.ErrorCode, e.ErrorDetails)
+}
+
+// DecodeNDJSON yields the newline delimited JSON documents of r as they are
+// read. Iteration stops at the first error.
+func DecodeNDJSON[R any](r io.Reader) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		decoder := json.NewDecoder(r)
+		for {
+			var item R
+			err := decoder.Decode(&item)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(item, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	defaultLogger     *slog.Logger
+	defaultLoggerOnce sync.Once
+)
+
+// Default returns the logger used when a client is built without one. It
+// writes through the logrus standard logger to preserve the library's
+// historical output.
+func Default() *slog.Logger {
+	defaultLoggerOnce.Do(func() {
+		defaultLogger = slog.New(NewLogrusHandler(logrus.StandardLogger()))
+	})
+	return defaultLogger
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	logrusHandler struct {
+		logger *logrus.Logger
+		fields logrus.Fields
+		group  string
+	}
+)
+
+// NewLogrusHandler adapts a logrus logger to slog, so connectors can keep
+// writing to the logger (level, formatter and hooks) the service already uses.
+func NewLogrusHandler(logger *logrus.Logger) slog.Handler {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &logrusHandler{logger: logger, fields: logrus.Fields{}}
+}
+
+func (h *logrusHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(toLogrusLevel(level))
+}
+
+func (h *logrusHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make(logrus.Fields, len(h.fields)+record.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		h.addAttr(fields, h.group, attr)
+		return true
+	})
+	h.logger.WithContext(ctx).WithFields(fields).Log(toLogrusLevel(record.Level), record.Message)
+	return nil
+}
+
+func (h *logrusHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(logrus.Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	for _, attr := range attrs {
+		h.addAttr(fields, h.group, attr)
+	}
+	return &logrusHandler{logger: h.logger, fields: fields, group: h.group}
+}
+
+func (h *logrusHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &logrusHandler{logger: h.logger, fields: h.fields, group: qualify(h.group, name)}
+}
+
+func (h *logrusHandler) addAttr(fields logrus.Fields, group string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		for _, nested := range value.Group() {
+			h.addAttr(fields, qualify(group, attr.Key), nested)
+		}
+		return
+	}
+	if attr.Key == "" {
+		return
+	}
+	fields[qualify(group, attr.Key)] = value.Any()
+}
+
+func qualify(group, key string) string {
+	if group == "" {
+		return key
+	}
+	if key == "" {
+		return group
+	}
+	return group + "." + key
+}
+
+func toLogrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return logrus.DebugLevel
+	case level < slog.LevelWarn:
+		return logrus.InfoLevel
+	case level < slog.LevelError:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	RedactedValue      = "[REDACTED]"
+	DefaultMaxBodySize = 2048
+)
+
+type (
+	// Lazy is a logged value built only when a handler emits it.
+	Lazy func() string
+
+	// Redactor masks sensitive headers and JSON body fields before they are
+	// logged, and truncates bodies to a maximum size.
+	Redactor struct {
+		headers     map[string]struct{}
+		fields      map[string]struct{}
+		maxBodySize int
+	}
+)
+
+func NewRedactor(headers []string, fields []string, maxBodySize int) *Redactor {
+	r := &Redactor{
+		headers:     map[string]struct{}{},
+		fields:      map[string]struct{}{},
+		maxBodySize: maxBodySize,
+	}
+	for _, h := range headers {
+		r.headers[strings.ToLower(h)] = struct{}{}
+	}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = struct{}{}
+	}
+	return r
+}
+
+func DefaultRedactor() *Redactor {
+	return NewRedactor(
+		[]string{"Authorization", "Proxy-Authorization", "x-api-key", "Cookie", "Set-Cookie", "X-Amz-Security-Token"},
+		[]string{"password", "secret", "token", "access_token", "refresh_token", "client_secret"},
+		DefaultMaxBodySize,
+	)
+}
+
+// WithHeaders returns a copy of the redactor that also masks the given headers.
+func (r *Redactor) WithHeaders(headers ...string) *Redactor {
+	c := r.clone()
+	for _, h := range headers {
+		c.headers[strings.ToLower(h)] = struct{}{}
+	}
+	return c
+}
+
+// WithFields returns a copy of the redactor that also masks the given JSON fields.
+func (r *Redactor) WithFields(fields ...string) *Redactor {
+	c := r.clone()
+	for _, f := range fields {
+		c.fields[strings.ToLower(f)] = struct{}{}
+	}
+	return c
+}
+
+// WithMaxBodySize returns a copy of the redactor truncating bodies to size
+// bytes. Zero or a negative size disables truncation.
+func (r *Redactor) WithMaxBodySize(size int) *Redactor {
+	c := r.clone()
+	c.maxBodySize = size
+	return c
+}
+
+func (r *Redactor) Header(key, value string) string {
+	if _, ok := r.headers[strings.ToLower(key)]; ok {
+		return RedactedValue
+	}
+	return value
+}
+
+func (r *Redactor) Headers(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for k, v := range headers {
+		result[k] = r.Header(k, v)
+	}
+	return result
+}
+
+// Body masks the configured fields when body is a JSON document and truncates
+// the result. Non JSON bodies are only truncated.
+func (r *Redactor) Body(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if doc, ok := r.decode(body); ok {
+		if masked, err := json.Marshal(r.mask(doc)); err == nil {
+			body = masked
+		}
+	}
+	return r.truncate(string(body))
+}
+
+// LogBody is Body evaluated only when the log line is emitted, so that bodies
+// are not decoded for disabled levels.
+func (r *Redactor) LogBody(body []byte) Lazy {
+	return func() string {
+		return r.Body(body)
+	}
+}
+
+// decode parses body when fields must be masked, keeping numbers as written
+// so that large ids are not logged as floats.
+func (r *Redactor) decode(body []byte) (interface{}, bool) {
+	if len(r.fields) == 0 {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if decoder.Decode(&doc) != nil || decoder.InputOffset() != int64(len(bytes.TrimRight(body, " \t\r\n"))) {
+		return nil, false
+	}
+	return doc, true
+}
+
+// Value renders v as JSON and applies the same rules as Body.
+func (r *Redactor) Value(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return r.truncate(fmt.Sprintf("%v", v))
+	}
+	return r.Body(body)
+}
+
+func (r *Redactor) mask(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if _, ok := r.fields[strings.ToLower(key)]; ok {
+				v[key] = RedactedValue
+				continue
+			}
+			v[key] = r.mask(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = r.mask(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) truncate(body string) string {
+	if r.maxBodySize <= 0 || len(body) <= r.maxBodySize {
+		return body
+	}
+	return fmt.Sprintf("%s...(%d bytes truncated)", body[:r.maxBodySize], len(body)-r.maxBodySize)
+}
+
+func (l Lazy) LogValue() slog.Value {
+	return slog.StringValue(l())
+}
+
+func (r *Redactor) clone() *Redactor {
+	c := NewRedactor(nil, nil, r.maxBodySize)
+	for h := range r.headers {
+		c.headers[h] = struct{}{}
+	}
+	for f := range r.fields {
+		c.fields[f] = struct{}{}
+	}
+	return c
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactorBodyKeepsNumbers(t *testing.T) {
+	body := DefaultRedactor().Body([]byte(`{"id":9007199254740993,"amount":1.50,"password":"p"}`))
+	for _, want := range []string{`"id":9007199254740993`, `"amount":1.50`, `"password":"[REDACTED]"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body %s does not contain %s", body, want)
+		}
+	}
+}
+
+func TestRedactorBodyLeavesInvalidJSON(t *testing.T) {
+	for _, body := range []string{`{"password":"p"} trailing`, `not json`} {
+		if got := DefaultRedactor().Body([]byte(body)); got != body {
+			t.Errorf("body = %q, want %q", got, body)
+		}
+	}
+}
+
+func TestLogBodyIsLazy(t *testing.T) {
+	var evaluated bool
+	var value Lazy = func() string {
+		evaluated = true
+		return "body"
+	}
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	logger.DebugContext(context.Background(), "disabled", "body", value)
+	if evaluated {
+		t.Fatal("body built for a disabled level")
+	}
+	logger.InfoContext(context.Background(), "enabled", "body", value)
+	if !evaluated || !strings.Contains(out.String(), "body=body") {
+		t.Fatalf("body not logged: %s", out.String())
+	}
+}